@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,9 +18,38 @@ import (
 var ImageGenerator pkg.ImageGenerator
 
 func parseConfig(cmd *cobra.Command, args []string) error {
-	var err error
-	ImageGenerator, err = pkg.ParseConfig(viper.GetString("config"))
-	return err
+	if !viper.GetBool("source-stdin") {
+		var err error
+		ImageGenerator, err = pkg.ParseConfig(viper.GetString("config"))
+		return err
+	}
+
+	config, err := pkg.ReadConfig(viper.GetString("config"))
+	if err != nil {
+		return err
+	}
+	config.UseStdin()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("config file is not valid: %w", err)
+	}
+	ImageGenerator = config
+	return nil
+}
+
+// outputFormat returns the format to write the image in, from the format flag or the extension of the output file.
+func outputFormat() (string, error) {
+	format := viper.GetString("format")
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(viper.GetString("output"))) {
+		case ".pbm", ".pgm", ".ppm", ".pnm":
+			return internal.FormatNetpbm, nil
+		}
+		return internal.FormatPNG, nil
+	}
+	if !slices.Contains(internal.OutputFormats, format) {
+		return "", fmt.Errorf("invalid format \"%s\", must be one of %s", format, strings.Join(internal.OutputFormats, ", "))
+	}
+	return format, nil
 }
 
 var GenerateCmd = &cobra.Command{
@@ -24,16 +58,30 @@ var GenerateCmd = &cobra.Command{
 	PreRunE: parseConfig,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
-		image, err := ImageGenerator.GenerateImage(viper.GetInt("width"), viper.GetInt("height"))
+		format, err := outputFormat()
 		if err != nil {
 			return err
 		}
 
+		internal.Stdin = cmd.InOrStdin()
+		image, err := ImageGenerator.GenerateImage(viper.GetInt("width"), viper.GetInt("height"))
+		var staleErr *pkg.StaleImageError
+		if err != nil && !errors.As(err, &staleErr) {
+			return err
+		}
+
+		var outputErr error
 		if viper.GetBool("to-stdout") {
-			return internal.EncodeImage(cmd.OutOrStdout(), image)
+			outputErr = internal.EncodeImage(cmd.OutOrStdout(), image, format)
 		} else {
-			return internal.WriteImage(viper.GetString("output"), image)
+			outputErr = internal.WriteImage(viper.GetString("output"), image, format)
 		}
+		if outputErr != nil {
+			return outputErr
+		}
+
+		// A stale image was still written, but the error is returned so that the exit status shows it
+		return err
 	},
 }
 
@@ -50,9 +98,12 @@ func init() {
 	GenerateCmd.Flags().Int("width", 0, "the width of the image")
 	_ = GenerateCmd.MarkFlagRequired("width")
 
+	GenerateCmd.Flags().Bool("source-stdin", false, "read the source image from stdin, instead of the source in the config file")
+
 	GenerateCmd.Flags().StringP("output", "o", DefaultOutputFilename, "path to write the file")
 	GenerateCmd.Flags().Bool("to-stdout", false, "print the image to stdout")
 	GenerateCmd.MarkFlagsMutuallyExclusive("output", "to-stdout")
+	GenerateCmd.Flags().String("format", "", "the format to write the image in: png or netpbm (defaults to netpbm for .pbm, .pgm, .ppm and .pnm files, and png otherwise)")
 	GenerateCmd.SetOut(os.Stdout)
 	_ = viper.BindPFlags(GenerateCmd.Flags())
 }
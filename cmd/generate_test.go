@@ -1,8 +1,11 @@
 package cmd_test
 
 import (
+	"bytes"
 	"errors"
 	"image"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/viper"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/petewall/eink-radiator-image-source-image/cmd"
 	"github.com/petewall/eink-radiator-image-source-image/internal"
 	"github.com/petewall/eink-radiator-image-source-image/internal/internalfakes"
+	"github.com/petewall/eink-radiator-image-source-image/pkg"
 	"github.com/petewall/eink-radiator-image-source-image/pkg/pkgfakes"
 )
 
@@ -37,6 +41,7 @@ var _ = Describe("Generate", func() {
 
 		viper.Set("to-stdout", false)
 		viper.Set("output", cmd.DefaultOutputFilename)
+		viper.Set("format", "")
 		viper.Set("height", 1000)
 		viper.Set("width", 2000)
 	})
@@ -52,9 +57,10 @@ var _ = Describe("Generate", func() {
 		By("defaulting to writing to image.png", func() {
 			Expect(imageEncoder.CallCount()).To(Equal(0))
 			Expect(imageWriter.CallCount()).To(Equal(1))
-			filename, writtenImage := imageWriter.ArgsForCall(0)
+			filename, writtenImage, format := imageWriter.ArgsForCall(0)
 			Expect(filename).To(Equal("image.png"))
 			Expect(writtenImage).To(Equal(img))
+			Expect(format).To(Equal(internal.FormatPNG))
 		})
 
 		By("using the right resolution", func() {
@@ -80,9 +86,10 @@ var _ = Describe("Generate", func() {
 
 			Expect(imageWriter.CallCount()).To(Equal(0))
 			Expect(imageEncoder.CallCount()).To(Equal(1))
-			out, encodedImage := imageEncoder.ArgsForCall(0)
+			out, encodedImage, format := imageEncoder.ArgsForCall(0)
 			Expect(out).To(Equal(output))
 			Expect(encodedImage).To(Equal(img))
+			Expect(format).To(Equal(internal.FormatPNG))
 		})
 
 		When("encoding fails", func() {
@@ -98,6 +105,51 @@ var _ = Describe("Generate", func() {
 		})
 	})
 
+	When("writing to a Netpbm file", func() {
+		BeforeEach(func() {
+			viper.Set("output", "frame.pgm")
+		})
+
+		It("writes the image in Netpbm format", func() {
+			err := cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(imageWriter.CallCount()).To(Equal(1))
+			filename, _, format := imageWriter.ArgsForCall(0)
+			Expect(filename).To(Equal("frame.pgm"))
+			Expect(format).To(Equal(internal.FormatNetpbm))
+		})
+	})
+
+	When("using --format", func() {
+		BeforeEach(func() {
+			viper.Set("format", "netpbm")
+		})
+
+		It("writes the image in that format", func() {
+			err := cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(imageWriter.CallCount()).To(Equal(1))
+			filename, _, format := imageWriter.ArgsForCall(0)
+			Expect(filename).To(Equal("image.png"))
+			Expect(format).To(Equal(internal.FormatNetpbm))
+		})
+
+		When("the format is not known", func() {
+			BeforeEach(func() {
+				viper.Set("format", "jpeg")
+			})
+
+			It("returns an error without generating the image", func() {
+				err := cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("invalid format \"jpeg\", must be one of png, netpbm"))
+				Expect(imageGenerator.GenerateImageCallCount()).To(Equal(0))
+			})
+		})
+	})
+
 	When("saving the image fails", func() {
 		BeforeEach(func() {
 			imageWriter.Returns(errors.New("save image failed"))
@@ -109,4 +161,65 @@ var _ = Describe("Generate", func() {
 			Expect(err.Error()).To(Equal("save image failed"))
 		})
 	})
+
+	When("the image is generated from the last known good copy", func() {
+		BeforeEach(func() {
+			imageGenerator.GenerateImageReturns(img, &pkg.StaleImageError{Err: errors.New("http get failed")})
+		})
+
+		It("saves the image and returns the stale image error", func() {
+			err := cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+			Expect(err).To(HaveOccurred())
+			var staleErr *pkg.StaleImageError
+			Expect(errors.As(err, &staleErr)).To(BeTrue())
+
+			Expect(imageWriter.CallCount()).To(Equal(1))
+			_, writtenImage, _ := imageWriter.ArgsForCall(0)
+			Expect(writtenImage).To(Equal(img))
+		})
+	})
+
+	When("generating the image fails", func() {
+		BeforeEach(func() {
+			imageGenerator.GenerateImageReturns(nil, errors.New("generate image failed"))
+		})
+
+		It("returns an error without saving", func() {
+			err := cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("generate image failed"))
+			Expect(imageWriter.CallCount()).To(Equal(0))
+		})
+	})
+
+	When("using --source-stdin", func() {
+		var configFile string
+
+		BeforeEach(func() {
+			configFile = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(configFile, []byte("scale: contain\n"), 0644)).To(Succeed())
+			viper.Set("config", configFile)
+			viper.Set("source-stdin", true)
+			DeferCleanup(func() {
+				viper.Set("source-stdin", false)
+				cmd.GenerateCmd.SetIn(os.Stdin)
+				internal.Stdin = os.Stdin
+			})
+		})
+
+		It("reads the source image from the command's input", func() {
+			err := cmd.GenerateCmd.PreRunE(cmd.GenerateCmd, []string{})
+			Expect(err).ToNot(HaveOccurred())
+			config, ok := cmd.ImageGenerator.(*pkg.Config)
+			Expect(ok).To(BeTrue())
+			Expect(config.Source).To(Equal(pkg.StdinSource))
+
+			input := bytes.NewBufferString("image data")
+			cmd.GenerateCmd.SetIn(input)
+			cmd.ImageGenerator = imageGenerator
+			err = cmd.GenerateCmd.RunE(cmd.GenerateCmd, []string{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(internal.Stdin).To(BeIdenticalTo(input))
+		})
+	})
 })
@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/petewall/eink-radiator-image-source-image/pkg"
 )
 
 const ImageTypeName = "image"
 
+const (
+	ExitCodeError = 1
+	ExitCodeStale = 2
+)
+
 var rootCmd = &cobra.Command{
 	Use:   ImageTypeName,
 	Short: "Generate an image from another image",
@@ -16,7 +24,11 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		var staleErr *pkg.StaleImageError
+		if errors.As(err, &staleErr) {
+			os.Exit(ExitCodeStale)
+		}
+		os.Exit(ExitCodeError)
 	}
 }
 
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+)
+
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+type NoArchiveImagesError struct {
+	Archive string
+}
+
+func (e *NoArchiveImagesError) Error() string {
+	return fmt.Sprintf("no usable images found in archive: %s", e.Archive)
+}
+
+// IsArchive returns true if the source looks like a zip or tar archive, from its file extension.
+func IsArchive(source string) bool {
+	name := source
+	if SourceScheme(source) != SchemeFile {
+		u, err := url.Parse(source)
+		if err != nil {
+			return false
+		}
+		name = u.Path
+	}
+	name = strings.ToLower(name)
+	return slices.ContainsFunc(archiveExtensions, func(extension string) bool {
+		return strings.HasSuffix(name, extension)
+	})
+}
+
+// OpenArchive opens a local archive file. Members are read from the file as they are needed.
+func OpenArchive(source string) (*os.File, int64, error) {
+	path, err := FilePath(source)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, info, err := openFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.IsDir() {
+		_ = f.Close()
+		return nil, 0, fmt.Errorf("archive source is a directory, not a file: %s", path)
+	}
+	return f, info.Size(), nil
+}
+
+// DetectArchiveFormat returns the format of the archive from its first bytes, or an empty string if it is not a known archive.
+func DetectArchiveFormat(r io.ReaderAt) string {
+	header := make([]byte, 512)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return ArchiveTar
+	}
+	return ""
+}
+
+// ListArchiveImages returns the sorted names of the image files in the archive that match the options.
+func ListArchiveImages(r io.ReaderAt, size int64, options *DirectoryOptions) ([]string, error) {
+	names := []string{}
+	err := walkArchive(r, size, func(name string, _ func() (io.Reader, error)) (bool, error) {
+		if options.Matches(name) {
+			names = append(names, name)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// ReadArchiveMember reads one file from the archive, without reading the rest of the archive into memory.
+func ReadArchiveMember(r io.ReaderAt, size int64, member string, maxBytes int64) ([]byte, error) {
+	var data []byte
+	found := false
+	err := walkArchive(r, size, func(name string, open func() (io.Reader, error)) (bool, error) {
+		if name != member {
+			return false, nil
+		}
+		found = true
+		reader, err := open()
+		if err != nil {
+			return true, err
+		}
+		data, err = io.ReadAll(LimitReader(reader, maxBytes))
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("archive does not contain %s", member)
+	}
+	return data, nil
+}
+
+// walkArchive calls visit for each regular file in the archive, until visit returns true.
+func walkArchive(r io.ReaderAt, size int64, visit func(name string, open func() (io.Reader, error)) (bool, error)) error {
+	switch DetectArchiveFormat(r) {
+	case ArchiveZip:
+		archive, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("failed to read zip archive: %w", err)
+		}
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			var opened io.ReadCloser
+			done, err := visit(cleanMemberName(file.Name), func() (io.Reader, error) {
+				var err error
+				opened, err = file.Open()
+				return opened, err
+			})
+			if opened != nil {
+				_ = opened.Close()
+			}
+			if done || err != nil {
+				return err
+			}
+		}
+		return nil
+	case ArchiveTar:
+		return walkTar(io.NewSectionReader(r, 0, size), visit)
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return fmt.Errorf("failed to read tar.gz archive: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		return walkTar(gz, visit)
+	}
+	return fmt.Errorf("not a zip or tar archive")
+}
+
+func walkTar(r io.Reader, visit func(name string, open func() (io.Reader, error)) (bool, error)) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		done, err := visit(cleanMemberName(header.Name), func() (io.Reader, error) { return archive, nil })
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+// cleanMemberName removes any leading "./" or "/" from the name of a file in an archive.
+func cleanMemberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
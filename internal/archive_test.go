@@ -0,0 +1,107 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var archiveFiles = map[string]string{
+	"./summer/beach.jpg":         "beach image data",
+	"summer/notes.txt":           "not an image",
+	"winter/snow.png":            "snow image data",
+	"__MACOSX/winter/._snow.png": "resource fork",
+}
+
+func makeZip() []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	_, err := w.Create("summer/")
+	Expect(err).ToNot(HaveOccurred())
+	for name, contents := range archiveFiles {
+		f, err := w.Create(name)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write([]byte(contents))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func makeTar() []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	Expect(w.WriteHeader(&tar.Header{Name: "summer/", Typeflag: tar.TypeDir, Mode: 0755})).To(Succeed())
+	for name, contents := range archiveFiles {
+		Expect(w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))})).To(Succeed())
+		_, err := w.Write([]byte(contents))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func makeTarGz() []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(makeTar())
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("Archives", func() {
+	DescribeTable("reading images from archives",
+		func(makeArchive func() []byte, format string) {
+			data := makeArchive()
+			archive := bytes.NewReader(data)
+			Expect(internal.DetectArchiveFormat(archive)).To(Equal(format))
+
+			names, err := internal.ListArchiveImages(archive, int64(len(data)), &internal.DirectoryOptions{Recursive: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"summer/beach.jpg", "winter/snow.png"}))
+
+			names, err = internal.ListArchiveImages(archive, int64(len(data)), &internal.DirectoryOptions{Recursive: true, Include: []string{"winter/*"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(Equal([]string{"winter/snow.png"}))
+
+			member, err := internal.ReadArchiveMember(archive, int64(len(data)), "winter/snow.png", 1024)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(member)).To(Equal("snow image data"))
+
+			_, err = internal.ReadArchiveMember(archive, int64(len(data)), "summer/beach.jpg", 4)
+			var limitErr *internal.LimitError
+			Expect(errors.As(err, &limitErr)).To(BeTrue())
+
+			_, err = internal.ReadArchiveMember(archive, int64(len(data)), "autumn/leaves.jpg", 1024)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("archive does not contain autumn/leaves.jpg"))
+		},
+		Entry("zip", makeZip, internal.ArchiveZip),
+		Entry("tar", makeTar, internal.ArchiveTar),
+		Entry("tar.gz", makeTarGz, internal.ArchiveTarGz),
+	)
+
+	It("rejects data that is not an archive", func() {
+		_, err := internal.ListArchiveImages(bytes.NewReader([]byte("just some text")), 14, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("not a zip or tar archive"))
+	})
+
+	DescribeTable("IsArchive",
+		func(source string, expected bool) {
+			Expect(internal.IsArchive(source)).To(Equal(expected))
+		},
+		Entry("zip file", "/home/pi/photos.zip", true),
+		Entry("tar.gz URL", "https://example.com/dump.tar.gz?token=abc", true),
+		Entry("tgz in S3", "s3://photos/dump.TGZ", true),
+		Entry("image", "https://example.com/zip.jpg", false),
+	)
+})
@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultCacheMaxSize = 100 * 1024 * 1024
+
+type CacheOptions struct {
+	Directory string
+	MaxSize   int64
+	MaxAge    time.Duration
+}
+
+// DefaultCacheDirectory returns the directory used for cached data when one is not configured.
+func DefaultCacheDirectory() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "eink-radiator-image")
+}
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	ContentType  string    `json:"contentType,omitempty"`
+	Validated    time.Time `json:"validated"`
+}
+
+type cache struct {
+	directory string
+	maxSize   int64
+	maxAge    time.Duration
+}
+
+func newCache(options *CacheOptions) *cache {
+	c := &cache{
+		directory: options.Directory,
+		maxSize:   options.MaxSize,
+		maxAge:    options.MaxAge,
+	}
+	if c.directory == "" {
+		c.directory = filepath.Join(DefaultCacheDirectory(), "http")
+	}
+	if c.maxSize <= 0 {
+		c.maxSize = DefaultCacheMaxSize
+	}
+	return c
+}
+
+func (c *cache) path(source, extension string) string {
+	hash := sha256.Sum256([]byte(source))
+	return filepath.Join(c.directory, hex.EncodeToString(hash[:])+extension)
+}
+
+func (c *cache) load(source string) *cacheEntry {
+	data, err := os.ReadFile(c.path(source, ".json"))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != source {
+		return nil
+	}
+	if _, err := os.Stat(c.path(source, ".data")); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func (c *cache) save(entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(entry.URL, ".json"), data, 0644)
+}
+
+func (c *cache) response(entry *cacheEntry) (*http.Response, error) {
+	dataPath := c.path(entry.URL, ".data")
+	f, info, err := openFile(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	touched := time.Now()
+	_ = os.Chtimes(dataPath, touched, touched)
+
+	header := http.Header{}
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.0",
+		ProtoMajor:    1,
+		Header:        header,
+		Body:          f,
+		ContentLength: info.Size(),
+	}, nil
+}
+
+// store replaces the response body with one that also writes the data into the cache.
+// The cache entry is only saved if the whole body is read.
+func (c *cache) store(source string, res *http.Response) {
+	if err := os.MkdirAll(c.directory, 0755); err != nil {
+		Logger.Printf("unable to create cache directory %s: %s", c.directory, err)
+		return
+	}
+	tmp, err := os.CreateTemp(c.directory, "download-*")
+	if err != nil {
+		Logger.Printf("unable to write to cache directory %s: %s", c.directory, err)
+		return
+	}
+
+	res.Body = &cachingBody{
+		body:  res.Body,
+		tmp:   tmp,
+		cache: c,
+		entry: &cacheEntry{
+			URL:          source,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			ContentType:  res.Header.Get("Content-Type"),
+			Validated:    Now(),
+		},
+	}
+}
+
+type cachingBody struct {
+	body     io.ReadCloser
+	tmp      *os.File
+	cache    *cache
+	entry    *cacheEntry
+	complete bool
+	failed   bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 && !b.failed {
+		if _, writeErr := b.tmp.Write(p[:n]); writeErr != nil {
+			b.failed = true
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		b.complete = true
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	err := b.body.Close()
+	tmpErr := b.tmp.Close()
+	if !b.complete || b.failed || tmpErr != nil {
+		_ = os.Remove(b.tmp.Name())
+		return err
+	}
+
+	if renameErr := os.Rename(b.tmp.Name(), b.cache.path(b.entry.URL, ".data")); renameErr != nil {
+		_ = os.Remove(b.tmp.Name())
+		return err
+	}
+	if saveErr := b.cache.save(b.entry); saveErr != nil {
+		Logger.Printf("unable to save cache entry for %s: %s", RedactURL(b.entry.URL), saveErr)
+	}
+	b.cache.prune()
+	return err
+}
+
+// prune removes the least recently used entries until the cache fits within its maximum size.
+func (c *cache) prune() {
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return
+	}
+
+	var files []fs.FileInfo
+	var total int64
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".data") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, file := range files {
+		if total <= c.maxSize {
+			break
+		}
+		base := strings.TrimSuffix(file.Name(), ".data")
+		_ = os.Remove(filepath.Join(c.directory, base+".data"))
+		_ = os.Remove(filepath.Join(c.directory, base+".json"))
+		total -= file.Size()
+	}
+}
+
+func getCached(client *http.Client, source string, options *HttpOptions) (*http.Response, error) {
+	c := newCache(options.Cache)
+	entry := c.load(source)
+	if entry != nil && c.maxAge > 0 && Now().Sub(entry.Validated) < c.maxAge {
+		Logger.Printf("using cached copy of %s", RedactURL(source))
+		return c.response(entry)
+	}
+
+	header := http.Header{}
+	if entry != nil {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := doWithRetries(client, source, options, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		_ = res.Body.Close()
+		Logger.Printf("%s has not been modified, using cached copy", RedactURL(source))
+		entry.Validated = Now()
+		if etag := res.Header.Get("ETag"); etag != "" {
+			entry.ETag = etag
+		}
+		if err := c.save(entry); err != nil {
+			Logger.Printf("unable to save cache entry for %s: %s", RedactURL(source), err)
+		}
+		return c.response(entry)
+	}
+
+	if res.StatusCode == http.StatusOK {
+		c.store(source, res)
+	}
+	return res, nil
+}
@@ -0,0 +1,130 @@
+package internal_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("Caching downloads", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+		content  string
+		now      time.Time
+		options  *internal.HttpOptions
+	)
+
+	fetch := func(source string) string {
+		res, err := internal.HttpGet(source, options)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() { Expect(res.Body.Close()).To(Succeed()) }()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(data)
+	}
+
+	BeforeEach(func() {
+		requests = []*http.Request{}
+		content = "image data"
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			etag := `"` + content + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(content))
+		}))
+		DeferCleanup(server.Close)
+
+		now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		internal.Now = func() time.Time { return now }
+		DeferCleanup(func() { internal.Now = time.Now })
+		internal.Logger = log.New(GinkgoWriter, "", 0)
+
+		options = &internal.HttpOptions{
+			Cache: &internal.CacheOptions{
+				Directory: GinkgoT().TempDir(),
+			},
+		}
+	})
+
+	It("revalidates the cached copy and uses it when it has not been modified", func() {
+		Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Header.Get("If-None-Match")).To(BeEmpty())
+
+		Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1].Header.Get("If-None-Match")).To(Equal(`"image data"`))
+	})
+
+	It("replaces the cached copy when the source has changed", func() {
+		Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+		content = "new image data"
+		Expect(fetch(server.URL + "/image.png")).To(Equal("new image data"))
+		Expect(fetch(server.URL + "/image.png")).To(Equal("new image data"))
+		Expect(requests).To(HaveLen(3))
+		Expect(requests[2].Header.Get("If-None-Match")).To(Equal(`"new image data"`))
+	})
+
+	It("does not cache a partially read download", func() {
+		res, err := internal.HttpGet(server.URL+"/image.png", options)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+
+		Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+		Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+	})
+
+	When("the cached copy is younger than the max age", func() {
+		BeforeEach(func() {
+			options.Cache.MaxAge = time.Hour
+		})
+
+		It("does not contact the server", func() {
+			Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+			now = now.Add(30 * time.Minute)
+			Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+			Expect(requests).To(HaveLen(1))
+
+			now = now.Add(time.Hour)
+			Expect(fetch(server.URL + "/image.png")).To(Equal("image data"))
+			Expect(requests).To(HaveLen(2))
+		})
+	})
+
+	When("the cache is larger than the max size", func() {
+		BeforeEach(func() {
+			options.Cache.MaxSize = 15
+		})
+
+		It("removes the least recently used entries", func() {
+			Expect(fetch(server.URL + "/first.png")).To(Equal("image data"))
+			files, err := filepath.Glob(filepath.Join(options.Cache.Directory, "*.data"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			old := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(files[0], old, old)).To(Succeed())
+
+			Expect(fetch(server.URL + "/second.png")).To(Equal("image data"))
+			remaining, err := filepath.Glob(filepath.Join(options.Cache.Directory, "*.data"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining).To(HaveLen(1))
+			Expect(remaining[0]).ToNot(Equal(files[0]))
+		})
+	})
+})
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type DataURI struct {
+	MediaType string
+	Data      []byte
+}
+
+// ParseDataURI decodes an RFC 2397 data URI, which must contain an image.
+func ParseDataURI(source string) (*DataURI, error) {
+	if !strings.HasPrefix(strings.ToLower(source), SchemeData+":") {
+		return nil, fmt.Errorf("not a data URI")
+	}
+
+	header, payload, found := strings.Cut(source[len(SchemeData)+1:], ",")
+	if !found {
+		return nil, fmt.Errorf("data URI is missing a comma before the data")
+	}
+
+	isBase64 := false
+	params := strings.Split(header, ";")
+	if strings.EqualFold(params[len(params)-1], "base64") {
+		isBase64 = true
+		params = params[:len(params)-1]
+	}
+
+	mediaType := "text/plain"
+	if params[0] != "" {
+		parsed, _, err := mime.ParseMediaType(strings.Join(params, ";"))
+		if err != nil {
+			return nil, fmt.Errorf("data URI has an invalid media type: %w", err)
+		}
+		mediaType = parsed
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("data URI media type is not an image: \"%s\"", mediaType)
+	}
+
+	var data []byte
+	var err error
+	if isBase64 {
+		data, err = decodeBase64(payload)
+		if err != nil {
+			return nil, fmt.Errorf("data URI has invalid base64 data: %w", err)
+		}
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("data URI has invalid percent-encoded data: %w", err)
+		}
+		data = []byte(unescaped)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("data URI contains no data")
+	}
+
+	return &DataURI{MediaType: mediaType, Data: data}, nil
+}
+
+// DescribeSource returns a short form of the source that is suitable for logs and error messages.
+func DescribeSource(source string) string {
+	if SourceScheme(source) != SchemeData {
+		return source
+	}
+	header, _, _ := strings.Cut(source, ",")
+	return fmt.Sprintf("%s,... (%d bytes)", header, len(source))
+}
+
+func decodeBase64(payload string) ([]byte, error) {
+	payload, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, err
+	}
+	payload = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, payload)
+	if strings.HasSuffix(payload, "=") || len(payload)%4 == 0 {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	return base64.RawStdEncoding.DecodeString(payload)
+}
+
+func getData(source string, _ *HttpOptions) (*http.Response, error) {
+	dataURI, err := ParseDataURI(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.0",
+		ProtoMajor:    1,
+		Header:        http.Header{"Content-Type": []string{dataURI.MediaType}},
+		Body:          io.NopCloser(bytes.NewReader(dataURI.Data)),
+		ContentLength: int64(len(dataURI.Data)),
+	}, nil
+}
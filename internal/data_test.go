@@ -0,0 +1,53 @@
+package internal_test
+
+import (
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("Data URIs", func() {
+	It("decodes base64 data", func() {
+		dataURI, err := internal.ParseDataURI("data:image/png;base64,aW1hZ2UgZGF0YQ==")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dataURI.MediaType).To(Equal("image/png"))
+		Expect(string(dataURI.Data)).To(Equal("image data"))
+	})
+
+	It("decodes percent-encoded data", func() {
+		dataURI, err := internal.ParseDataURI("data:image/svg+xml;charset=utf-8,%3Csvg%2F%3E")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dataURI.MediaType).To(Equal("image/svg+xml"))
+		Expect(string(dataURI.Data)).To(Equal("<svg/>"))
+	})
+
+	It("is fetched without any network access", func() {
+		res, err := internal.HttpGet("data:image/png;base64,aW1hZ2UgZGF0YQ==", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Header.Get("Content-Type")).To(Equal("image/png"))
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+	})
+
+	It("is shortened when described", func() {
+		Expect(internal.DescribeSource("data:image/png;base64,aW1hZ2UgZGF0YQ==")).To(Equal("data:image/png;base64,... (38 bytes)"))
+		Expect(internal.DescribeSource("https://www.example.com/image.jpg")).To(Equal("https://www.example.com/image.jpg"))
+	})
+
+	DescribeTable("rejects malformed data URIs",
+		func(source, message string) {
+			_, err := internal.ParseDataURI(source)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(message))
+		},
+		Entry("no comma", "data:image/png;base64", "data URI is missing a comma before the data"),
+		Entry("not an image", "data:text/plain;base64,aW1hZ2UgZGF0YQ==", "data URI media type is not an image: \"text/plain\""),
+		Entry("default media type", "data:,hello", "data URI media type is not an image: \"text/plain\""),
+		Entry("bad base64", "data:image/png;base64,!!!!", "data URI has invalid base64 data: illegal base64 data at input byte 0"),
+		Entry("no data", "data:image/png;base64,", "data URI contains no data"),
+	)
+})
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type DirectoryOptions struct {
+	Include    []string
+	Exclude    []string
+	Recursive  bool
+	Extensions []string
+}
+
+type NoImagesError struct {
+	Directory string
+}
+
+func (e *NoImagesError) Error() string {
+	return fmt.Sprintf("no usable images found in directory: %s", e.Directory)
+}
+
+// IsDirectory returns true if the source refers to a local directory.
+func IsDirectory(source string) bool {
+	if SourceScheme(source) != SchemeFile {
+		return false
+	}
+	path, err := FilePath(source)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(rel)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the image file, given by its slash-separated path relative to the directory, should be used.
+func (o *DirectoryOptions) Matches(rel string) bool {
+	if o == nil {
+		o = &DirectoryOptions{}
+	}
+	extensions := o.Extensions
+	if len(extensions) == 0 {
+		extensions = ImageExtensions
+	}
+
+	parts := strings.Split(rel, "/")
+	if len(parts) > 1 && !o.Recursive {
+		return false
+	}
+	for i, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+		if i < len(parts)-1 && matchesAny(o.Exclude, strings.Join(parts[:i+1], "/")) {
+			return false
+		}
+	}
+
+	if !slices.Contains(extensions, strings.ToLower(path.Ext(rel))) {
+		return false
+	}
+	if len(o.Include) > 0 && !matchesAny(o.Include, rel) {
+		return false
+	}
+	return !matchesAny(o.Exclude, rel)
+}
+
+// ValidateGlob returns an error if the pattern is malformed.
+func ValidateGlob(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// ListImageFiles returns the sorted paths of the image files in the directory.
+// Include and exclude patterns are matched against both the file name and the path relative to the directory.
+// Hidden files and directories are skipped.
+func ListImageFiles(directory string, options *DirectoryOptions) ([]string, error) {
+	if options == nil {
+		options = &DirectoryOptions{}
+	}
+
+	files := []string{}
+	err := filepath.WalkDir(directory, func(file string, entry fs.DirEntry, err error) error {
+		if file == directory {
+			return err
+		}
+		if err != nil {
+			Logger.Printf("skipping %s: %s", file, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(directory, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		hidden := strings.HasPrefix(entry.Name(), ".")
+
+		if entry.IsDir() {
+			if !options.Recursive || hidden || matchesAny(options.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if options.Matches(rel) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &FileNotFoundError{Path: directory, Err: err}
+		}
+		if os.IsPermission(err) {
+			return nil, &FilePermissionError{Path: directory, Err: err}
+		}
+		return nil, err
+	}
+
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,84 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ListImageFiles", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		for _, file := range []string{
+			"b.jpg",
+			"a.PNG",
+			"notes.txt",
+			".hidden.jpg",
+			"draft-c.jpg",
+			"vacation/d.jpeg",
+			"vacation/raw/e.gif",
+			".stfolder/f.jpg",
+		} {
+			path := filepath.Join(dir, file)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte("image data"), 0644)).To(Succeed())
+		}
+	})
+
+	It("lists the images in the directory", func() {
+		files, err := internal.ListImageFiles(dir, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{
+			filepath.Join(dir, "a.PNG"),
+			filepath.Join(dir, "b.jpg"),
+			filepath.Join(dir, "draft-c.jpg"),
+		}))
+	})
+
+	It("lists images in subdirectories when recursive", func() {
+		files, err := internal.ListImageFiles(dir, &internal.DirectoryOptions{Recursive: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{
+			filepath.Join(dir, "a.PNG"),
+			filepath.Join(dir, "b.jpg"),
+			filepath.Join(dir, "draft-c.jpg"),
+			filepath.Join(dir, "vacation/d.jpeg"),
+			filepath.Join(dir, "vacation/raw/e.gif"),
+		}))
+	})
+
+	It("filters with include and exclude patterns", func() {
+		files, err := internal.ListImageFiles(dir, &internal.DirectoryOptions{
+			Recursive: true,
+			Include:   []string{"*.jpg", "vacation/*"},
+			Exclude:   []string{"draft-*", "raw"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{
+			filepath.Join(dir, "b.jpg"),
+			filepath.Join(dir, "vacation/d.jpeg"),
+		}))
+	})
+
+	It("filters by extension", func() {
+		files, err := internal.ListImageFiles(dir, &internal.DirectoryOptions{
+			Extensions: []string{".png"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{filepath.Join(dir, "a.PNG")}))
+	})
+
+	When("the directory does not exist", func() {
+		It("returns an error", func() {
+			_, err := internal.ListImageFiles(filepath.Join(dir, "missing"), nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("image file does not exist: " + filepath.Join(dir, "missing")))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// exifOrientationTag is the EXIF tag that says how the camera was held, and so how the image must be turned to display it upright.
+const exifOrientationTag = 0x0112
+
+// jpegOrientation finds the EXIF orientation in the start of a JPEG file, or returns 1 (upright) if there is none.
+func jpegOrientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff:
+			// Fill byte
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd8):
+			// Markers without a length
+			i += 2
+			continue
+		case marker == 0xda || marker == 0xd9:
+			// The image data starts, so there is no EXIF data
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation from the first IFD of the TIFF structure in EXIF data.
+func exifOrientation(data []byte) int {
+	if len(data) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	// Offsets are compared as uint64, as they can be too large for an int on 32-bit systems
+	size := uint64(len(data))
+	offset := uint64(order.Uint32(data[4:8]))
+	if offset+2 > size {
+		return 1
+	}
+	count := uint64(order.Uint16(data[offset : offset+2]))
+	for i := range count {
+		entry := offset + 2 + i*12
+		if entry+12 > size {
+			return 1
+		}
+		if order.Uint16(data[entry:entry+2]) == exifOrientationTag {
+			orientation := int(order.Uint16(data[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation flips and rotates the image, as described by an EXIF orientation, so that it is upright.
+func applyOrientation(im image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return im
+	}
+
+	bounds := im.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	size := image.Pt(w, h)
+	if orientation >= 5 {
+		size = image.Pt(h, w)
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180 degrees
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // Mirrored along the top-left to bottom-right diagonal
+				dx, dy = y, x
+			case 6: // Needs rotating 90 degrees clockwise
+				dx, dy = h-1-y, x
+			case 7: // Mirrored along the top-right to bottom-left diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // Needs rotating 90 degrees counterclockwise
+				dx, dy = y, w-1-x
+			}
+			c := color.RGBAModel.Convert(im.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			offset := dst.PixOffset(dx, dy)
+			dst.Pix[offset], dst.Pix[offset+1], dst.Pix[offset+2], dst.Pix[offset+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return dst
+}
@@ -0,0 +1,128 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// jpegWithOrientation encodes a 32x16 image, with the top left quarter black and the rest white,
+// and adds an EXIF segment with the given orientation.
+func jpegWithOrientation(orientation int, order binary.AppendByteOrder) []byte {
+	im := image.NewGray(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			if x >= 16 || y >= 8 {
+				im.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	var encoded bytes.Buffer
+	Expect(jpeg.Encode(&encoded, im, &jpeg.Options{Quality: 100})).To(Succeed())
+
+	tiff := []byte("MM")
+	if order == binary.LittleEndian {
+		tiff = []byte("II")
+	}
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, uint16(orientation))
+	tiff = order.AppendUint16(tiff, 0)
+	tiff = order.AppendUint32(tiff, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xff, 0xd8, 0xff, 0xe1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// blackCorner returns which corner of the image is black: "top left", "top right", "bottom right" or "bottom left".
+func blackCorner(im image.Image) string {
+	bounds := im.Bounds()
+	corners := map[string]image.Point{
+		"top left":     {bounds.Min.X + 2, bounds.Min.Y + 2},
+		"top right":    {bounds.Max.X - 3, bounds.Min.Y + 2},
+		"bottom right": {bounds.Max.X - 3, bounds.Max.Y - 3},
+		"bottom left":  {bounds.Min.X + 2, bounds.Max.Y - 3},
+	}
+	found := ""
+	for name, p := range corners {
+		if gray := color.GrayModel.Convert(im.At(p.X, p.Y)).(color.Gray); gray.Y < 64 {
+			Expect(found).To(BeEmpty(), "more than one corner is black")
+			found = name
+		}
+	}
+	return found
+}
+
+var _ = Describe("EXIF orientation", func() {
+	DescribeTable("turns JPEG images upright",
+		func(orientation int, width, height int, corner string) {
+			for _, order := range []binary.AppendByteOrder{binary.BigEndian, binary.LittleEndian} {
+				im, err := internal.DecodeImage(bytes.NewReader(jpegWithOrientation(orientation, order)), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(im.Bounds().Dx()).To(Equal(width))
+				Expect(im.Bounds().Dy()).To(Equal(height))
+				Expect(blackCorner(im)).To(Equal(corner))
+			}
+		},
+		Entry("1: upright", 1, 32, 16, "top left"),
+		Entry("2: mirrored horizontally", 2, 32, 16, "top right"),
+		Entry("3: rotated 180 degrees", 3, 32, 16, "bottom right"),
+		Entry("4: mirrored vertically", 4, 32, 16, "bottom left"),
+		Entry("5: transposed", 5, 16, 32, "top left"),
+		Entry("6: rotated 90 degrees", 6, 16, 32, "top right"),
+		Entry("7: transversed", 7, 16, 32, "bottom right"),
+		Entry("8: rotated 270 degrees", 8, 16, 32, "bottom left"),
+		Entry("an invalid orientation", 9, 32, 16, "top left"),
+	)
+
+	It("leaves the image as stored when the orientation is ignored", func() {
+		im, err := internal.DecodeImage(bytes.NewReader(jpegWithOrientation(6, binary.BigEndian)), &internal.DecodeOptions{IgnoreOrientation: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds().Dx()).To(Equal(32))
+		Expect(im.Bounds().Dy()).To(Equal(16))
+		Expect(blackCorner(im)).To(Equal("top left"))
+	})
+
+	DescribeTable("ignores offsets outside the EXIF data",
+		func(offset uint32) {
+			data := jpegWithOrientation(6, binary.BigEndian)
+			// The TIFF header starts after the segment marker, its length and "Exif\0\0"
+			binary.BigEndian.PutUint32(data[12+4:12+8], offset)
+
+			im, err := internal.DecodeImage(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(im.Bounds().Dx()).To(Equal(32))
+			Expect(blackCorner(im)).To(Equal("top left"))
+		},
+		Entry("an IFD past the end", uint32(0xFFFFFFF0)),
+		Entry("an IFD offset that overflows", uint32(0xFFFFFFFF)),
+		// The orientation value is read as the number of entries, which run past the end
+		Entry("an IFD whose entries are past the end", uint32(18)),
+	)
+
+	It("decodes JPEG images without EXIF data", func() {
+		data := jpegWithOrientation(1, binary.BigEndian)
+		// Skip the EXIF segment
+		length := int(binary.BigEndian.Uint16(data[4:6]))
+		data = append([]byte{0xff, 0xd8}, data[4+length:]...)
+
+		im, err := internal.DecodeImage(bytes.NewReader(data), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds().Dx()).To(Equal(32))
+		Expect(blackCorner(im)).To(Equal("top left"))
+	})
+})
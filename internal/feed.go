@@ -0,0 +1,273 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FeedItem is an entry in an RSS or Atom feed that has an image.
+type FeedItem struct {
+	Title     string
+	Link      string
+	ImageURL  string
+	Published time.Time
+}
+
+type NoFeedImagesError struct {
+	Feed string
+}
+
+func (e *NoFeedImagesError) Error() string {
+	return fmt.Sprintf("no items with images found in feed: %s", e.Feed)
+}
+
+// UnsafeURLError is returned for an image URL found in a document that would be fetched in a way the document's author should not control,
+// such as a local file named by a remote feed.
+type UnsafeURLError struct {
+	URL      string
+	Document string
+}
+
+func (e *UnsafeURLError) Error() string {
+	return fmt.Sprintf("refusing to fetch %s, found in %s: links must use http, https or the same scheme as the document", DescribeSource(e.URL), DescribeSource(e.Document))
+}
+
+type mediaContent struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+	Width  int    `xml:"width,attr"`
+}
+
+type mediaThumbnail struct {
+	URL   string `xml:"url,attr"`
+	Width int    `xml:"width,attr"`
+}
+
+type mediaElements struct {
+	Contents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Groups     []struct {
+		Contents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type rssDocument struct {
+	Items []struct {
+		Title      string `xml:"title"`
+		Link       string `xml:"link"`
+		PubDate    string `xml:"pubDate"`
+		Date       string `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Enclosures []struct {
+			URL  string `xml:"url,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"enclosure"`
+		mediaElements
+	} `xml:"channel>item"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomDocument struct {
+	Entries []struct {
+		Title     string     `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		mediaElements
+	} `xml:"entry"`
+}
+
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+}
+
+func parseFeedDate(values ...string) time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		for _, layout := range feedDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func isImageType(mediaType string) bool {
+	return strings.HasPrefix(strings.ToLower(mediaType), "image/")
+}
+
+func hasImageExtension(location string) bool {
+	if u, err := url.Parse(location); err == nil {
+		location = u.Path
+	}
+	return slices.Contains(ImageExtensions, strings.ToLower(path.Ext(location)))
+}
+
+// imageURL picks the best image from the Media RSS elements of an item: the widest image content, then the widest thumbnail.
+func (m *mediaElements) imageURL() string {
+	contents := slices.Clone(m.Contents)
+	thumbnails := slices.Clone(m.Thumbnails)
+	for _, group := range m.Groups {
+		contents = append(contents, group.Contents...)
+		thumbnails = append(thumbnails, group.Thumbnails...)
+	}
+
+	best, bestWidth := "", -1
+	for _, content := range contents {
+		isImage := content.Medium == "image" || isImageType(content.Type) ||
+			(content.Medium == "" && content.Type == "" && hasImageExtension(content.URL))
+		if content.URL != "" && isImage && content.Width > bestWidth {
+			best, bestWidth = content.URL, content.Width
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	for _, thumbnail := range thumbnails {
+		if thumbnail.URL != "" && thumbnail.Width > bestWidth {
+			best, bestWidth = thumbnail.URL, thumbnail.Width
+		}
+	}
+	return best
+}
+
+// ResolveURL resolves a URL found in a document, which may be relative, against the document's location.
+func ResolveURL(document, location string) string {
+	location = strings.TrimSpace(location)
+	base, err := url.Parse(document)
+	if err != nil || location == "" {
+		return location
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// CheckLinkedURL checks that a URL found in a document, after it has been resolved, is safe to fetch.
+// Only http and https URLs are allowed, unless the URL uses the same scheme as the document,
+// so that a remote document cannot read local files, data: URIs or S3 objects using the local credentials.
+func CheckLinkedURL(document, location string) error {
+	scheme := SourceScheme(location)
+	if scheme == SchemeHttp || scheme == SchemeHttps || scheme == SourceScheme(document) {
+		return nil
+	}
+	return &UnsafeURLError{URL: location, Document: document}
+}
+
+// ParseFeed returns the items in an RSS 2.0 or Atom feed that have an image, in the order they appear in the feed.
+// Images are found in Media RSS content and thumbnails, enclosures, and Atom enclosure links.
+// Relative URLs are resolved against the feed's location.
+func ParseFeed(data []byte, location string) ([]FeedItem, error) {
+	root, err := feedRootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []FeedItem{}
+	switch root {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		for _, item := range doc.Items {
+			image := item.imageURL()
+			if image == "" {
+				for _, enclosure := range item.Enclosures {
+					if isImageType(enclosure.Type) || (enclosure.Type == "" && hasImageExtension(enclosure.URL)) {
+						image = enclosure.URL
+						break
+					}
+				}
+			}
+			if image == "" {
+				continue
+			}
+			items = append(items, FeedItem{
+				Title:     strings.TrimSpace(item.Title),
+				Link:      ResolveURL(location, item.Link),
+				ImageURL:  ResolveURL(location, image),
+				Published: parseFeedDate(item.PubDate, item.Date),
+			})
+		}
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		for _, entry := range doc.Entries {
+			var link string
+			image := entry.imageURL()
+			for _, l := range entry.Links {
+				switch l.Rel {
+				case "", "alternate":
+					if link == "" {
+						link = l.Href
+					}
+				case "enclosure":
+					if image == "" && (isImageType(l.Type) || (l.Type == "" && hasImageExtension(l.Href))) {
+						image = l.Href
+					}
+				}
+			}
+			if image == "" {
+				continue
+			}
+			items = append(items, FeedItem{
+				Title:     strings.TrimSpace(entry.Title),
+				Link:      ResolveURL(location, link),
+				ImageURL:  ResolveURL(location, image),
+				Published: parseFeedDate(entry.Published, entry.Updated),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("not an RSS or Atom feed: root element is <%s>", root)
+	}
+	return items, nil
+}
+
+func feedRootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// NewestFeedItem returns the most recently published item, or the first item if the feed has no dates.
+func NewestFeedItem(items []FeedItem) FeedItem {
+	newest := items[0]
+	for _, item := range items[1:] {
+		if item.Published.After(newest.Published) {
+			newest = item
+		}
+	}
+	return newest
+}
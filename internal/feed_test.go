@@ -0,0 +1,158 @@
+package internal_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ParseFeed", func() {
+	It("parses an RSS feed with Media RSS and enclosures", func() {
+		feed := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Photo of the day</title>
+    <item>
+      <title>Mountains</title>
+      <link>https://photos.example.com/mountains</link>
+      <pubDate>Sat, 17 Oct 2026 08:00:00 +0000</pubDate>
+      <media:thumbnail url="https://photos.example.com/mountains-thumb.jpg" width="150"/>
+      <media:group>
+        <media:content url="https://photos.example.com/mountains-small.jpg" medium="image" width="640"/>
+        <media:content url="https://photos.example.com/mountains-large.jpg" medium="image" width="1920"/>
+        <media:content url="https://photos.example.com/mountains.mp4" medium="video" width="3840"/>
+      </media:group>
+    </item>
+    <item>
+      <title>Beach</title>
+      <link>/beach</link>
+      <pubDate>Sun, 18 Oct 2026 08:00:00 +0000</pubDate>
+      <enclosure url="/images/beach.jpg" type="image/jpeg" length="12345"/>
+    </item>
+    <item>
+      <title>Forest</title>
+      <media:thumbnail url="https://photos.example.com/forest-thumb.jpg"/>
+    </item>
+    <item>
+      <title>Podcast episode</title>
+      <enclosure url="https://photos.example.com/episode.mp3" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`)
+
+		items, err := internal.ParseFeed(feed, "https://photos.example.com/feed.xml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal([]internal.FeedItem{
+			{
+				Title:     "Mountains",
+				Link:      "https://photos.example.com/mountains",
+				ImageURL:  "https://photos.example.com/mountains-large.jpg",
+				Published: time.Date(2026, time.October, 17, 8, 0, 0, 0, time.UTC),
+			},
+			{
+				Title:     "Beach",
+				Link:      "https://photos.example.com/beach",
+				ImageURL:  "https://photos.example.com/images/beach.jpg",
+				Published: time.Date(2026, time.October, 18, 8, 0, 0, 0, time.UTC),
+			},
+			{
+				Title:    "Forest",
+				ImageURL: "https://photos.example.com/forest-thumb.jpg",
+			},
+		}))
+
+		newest := internal.NewestFeedItem(items)
+		Expect(newest.Title).To(Equal("Beach"))
+	})
+
+	It("parses an Atom feed", func() {
+		feed := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <title>Comics</title>
+  <entry>
+    <title>Episode 12</title>
+    <link rel="alternate" href="https://comics.example.com/12"/>
+    <link rel="enclosure" type="image/png" href="strips/12.png"/>
+    <updated>2026-10-18T06:00:00Z</updated>
+  </entry>
+  <entry>
+    <title>Episode 11</title>
+    <link href="https://comics.example.com/11"/>
+    <media:content url="https://comics.example.com/strips/11.png" type="image/png"/>
+    <published>2026-10-17T06:00:00Z</published>
+  </entry>
+  <entry>
+    <title>Announcement</title>
+    <link href="https://comics.example.com/news"/>
+  </entry>
+</feed>`)
+
+		items, err := internal.ParseFeed(feed, "https://comics.example.com/atom.xml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(items).To(Equal([]internal.FeedItem{
+			{
+				Title:     "Episode 12",
+				Link:      "https://comics.example.com/12",
+				ImageURL:  "https://comics.example.com/strips/12.png",
+				Published: time.Date(2026, time.October, 18, 6, 0, 0, 0, time.UTC),
+			},
+			{
+				Title:     "Episode 11",
+				Link:      "https://comics.example.com/11",
+				ImageURL:  "https://comics.example.com/strips/11.png",
+				Published: time.Date(2026, time.October, 17, 6, 0, 0, 0, time.UTC),
+			},
+		}))
+	})
+
+	When("the document is not a feed", func() {
+		It("returns an error", func() {
+			_, err := internal.ParseFeed([]byte("<html><body>Not found</body></html>"), "https://example.com/feed.xml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("not an RSS or Atom feed: root element is <html>"))
+		})
+	})
+
+	When("the document is not XML", func() {
+		It("returns an error", func() {
+			_, err := internal.ParseFeed([]byte(`{"items": []}`), "https://example.com/feed.xml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("failed to parse feed: "))
+		})
+	})
+})
+
+var _ = Describe("CheckLinkedURL", func() {
+	DescribeTable("allows http, https and same scheme links",
+		func(document, location string) {
+			Expect(internal.CheckLinkedURL(document, location)).To(Succeed())
+		},
+		Entry("https from https", "https://example.com/feed.xml", "https://cdn.example.com/a.jpg"),
+		Entry("http from https", "https://example.com/feed.xml", "http://example.com/a.jpg"),
+		Entry("https from a local file", "/home/pi/feed.xml", "https://example.com/a.jpg"),
+		Entry("a local file from a local file", "/home/pi/feed.xml", "/home/pi/a.jpg"),
+		Entry("a file URL from a local file", "/home/pi/feed.xml", "file:///home/pi/a.jpg"),
+		Entry("s3 from s3", "s3://bucket/feed.xml", "s3://bucket/a.jpg"),
+	)
+
+	DescribeTable("rejects other links",
+		func(document, location string) {
+			err := internal.CheckLinkedURL(document, location)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(&internal.UnsafeURLError{}))
+		},
+		Entry("a file URL from https", "https://example.com/feed.xml", "file:///tmp/secret.png"),
+		Entry("a local path from https", "https://example.com/feed.xml", "C:/secret.png"),
+		Entry("s3 from https", "https://example.com/feed.xml", "s3://bucket/secret.png"),
+		Entry("data from https", "https://example.com/feed.xml", "data:image/png;base64,AAAA"),
+		Entry("s3 from a local file", "/home/pi/feed.xml", "s3://bucket/a.jpg"),
+	)
+
+	It("describes the link and the document", func() {
+		err := internal.CheckLinkedURL("https://example.com/feed.xml", "file:///tmp/secret.png")
+		Expect(err.Error()).To(Equal("refusing to fetch file:///tmp/secret.png, found in https://example.com/feed.xml: links must use http, https or the same scheme as the document"))
+	})
+})
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type FileNotFoundError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("image file does not exist: %s", e.Path)
+}
+
+func (e *FileNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+type FilePermissionError struct {
+	Path string
+	Err  error
+}
+
+func (e *FilePermissionError) Error() string {
+	return fmt.Sprintf("permission denied reading image file: %s", e.Path)
+}
+
+func (e *FilePermissionError) Unwrap() error {
+	return e.Err
+}
+
+// FilePath converts a file:// URL, or a plain absolute or relative path, into a local path.
+func FilePath(source string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(source), SchemeFile+":") {
+		return source, nil
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URL cannot refer to a remote host: \"%s\"", u.Host)
+	}
+	if u.Opaque != "" {
+		return u.Opaque, nil
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("file URL is missing a path: \"%s\"", source)
+	}
+	return u.Path, nil
+}
+
+func openFile(path string) (*os.File, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, &FileNotFoundError{Path: path, Err: err}
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, nil, &FilePermissionError{Path: path, Err: err}
+		}
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+func getFile(source string, _ *HttpOptions) (*http.Response, error) {
+	path, err := FilePath(source)
+	if err != nil {
+		return nil, err
+	}
+
+	f, info, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		_ = f.Close()
+		return nil, fmt.Errorf("image source is a directory, not a file: %s", path)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.0",
+		ProtoMajor:    1,
+		Header:        http.Header{},
+		Body:          f,
+		ContentLength: info.Size(),
+	}, nil
+}
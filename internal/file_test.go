@@ -0,0 +1,107 @@
+package internal_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("Fetching local files", func() {
+	var (
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		path = filepath.Join(dir, "image.jpg")
+		Expect(os.WriteFile(path, []byte("image data"), 0644)).To(Succeed())
+	})
+
+	It("reads an absolute path", func() {
+		res, err := internal.HttpGet(path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.ContentLength).To(Equal(int64(10)))
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+	})
+
+	It("reads a file:// URL", func() {
+		res, err := internal.HttpGet("file://"+path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+	})
+
+	It("reads a relative path", func() {
+		wd, err := os.Getwd()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+		DeferCleanup(os.Chdir, wd)
+
+		res, err := internal.HttpGet("image.jpg", nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+	})
+
+	When("the file does not exist", func() {
+		It("returns a file not found error", func() {
+			_, err := internal.HttpGet(filepath.Join(dir, "missing.jpg"), nil)
+			Expect(err).To(HaveOccurred())
+			var notFound *internal.FileNotFoundError
+			Expect(errors.As(err, &notFound)).To(BeTrue())
+			Expect(err.Error()).To(Equal("image file does not exist: " + filepath.Join(dir, "missing.jpg")))
+		})
+	})
+
+	When("the file is not readable", func() {
+		BeforeEach(func() {
+			if os.Geteuid() == 0 {
+				Skip("file permissions are not enforced for root")
+			}
+			Expect(os.Chmod(path, 0)).To(Succeed())
+		})
+
+		It("returns a permission error", func() {
+			_, err := internal.HttpGet(path, nil)
+			Expect(err).To(HaveOccurred())
+			var permission *internal.FilePermissionError
+			Expect(errors.As(err, &permission)).To(BeTrue())
+			Expect(err.Error()).To(Equal("permission denied reading image file: " + path))
+		})
+	})
+
+	When("the file URL has a remote host", func() {
+		It("returns an error", func() {
+			_, err := internal.HttpGet("file://example.com/image.jpg", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("file URL cannot refer to a remote host: \"example.com\""))
+		})
+	})
+})
+
+var _ = Describe("SourceScheme", func() {
+	It("detects the scheme of the source", func() {
+		Expect(internal.SourceScheme("https://www.example.com/image.jpg")).To(Equal("https"))
+		Expect(internal.SourceScheme("HTTP://www.example.com/image.jpg")).To(Equal("http"))
+		Expect(internal.SourceScheme("file:///images/image.jpg")).To(Equal("file"))
+		Expect(internal.SourceScheme("/images/image.jpg")).To(Equal("file"))
+		Expect(internal.SourceScheme("images/image.jpg")).To(Equal("file"))
+		Expect(internal.SourceScheme("ftp://www.example.com/image.jpg")).To(Equal("ftp"))
+	})
+})
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ImageExtensions are the file extensions of the image formats that can be decoded.
+var ImageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".tif", ".tiff", ".svg", ".pbm", ".pgm", ".ppm", ".pnm", ".pam"}
+
+// UnsupportedFormatError is returned when the data is a known image format that cannot be decoded.
+type UnsupportedFormatError struct {
+	Format string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("unsupported image format: %s", e.Format)
+}
+
+type imageSignature struct {
+	format string
+	match  func(header []byte) bool
+}
+
+func prefix(magic ...string) func(header []byte) bool {
+	return func(header []byte) bool {
+		for _, m := range magic {
+			if bytes.HasPrefix(header, []byte(m)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// riff matches a RIFF container of the given type, such as WebP.
+func riff(kind string) func(header []byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == kind
+	}
+}
+
+// netpbm matches a Netpbm file with one of the given magic numbers, which must be followed by whitespace.
+func netpbm(numbers ...byte) func(header []byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 3 && header[0] == 'P' && slices.Contains(numbers, header[1]) &&
+			strings.IndexByte(" \t\r\n", header[2]) >= 0
+	}
+}
+
+// isoBrand matches an ISO base media file, such as HEIC or AVIF, with one of the given major brands.
+func isoBrand(brands ...string) func(header []byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 12 && string(header[4:8]) == "ftyp" && slices.Contains(brands, string(header[8:12]))
+	}
+}
+
+var imageSignatures = []imageSignature{
+	{format: "png", match: prefix("\x89PNG\r\n\x1a\n")},
+	{format: "jpeg", match: prefix("\xff\xd8\xff")},
+	{format: "gif", match: prefix("GIF87a", "GIF89a")},
+	{format: "webp", match: riff("WEBP")},
+	{format: "bmp", match: prefix("BM")},
+	{format: "tiff", match: prefix("II*\x00", "MM\x00*")},
+	{format: "svg", match: IsSvg},
+	{format: "pbm", match: netpbm('1', '4')},
+	{format: "pgm", match: netpbm('2', '5')},
+	{format: "ppm", match: netpbm('3', '6')},
+	{format: "pam", match: netpbm('7')},
+
+	// Formats that are recognized, so they can be reported, but cannot be decoded
+	{format: "avif", match: isoBrand("avif", "avis")},
+	{format: "heic", match: isoBrand("heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1")},
+	{format: "jpeg xl", match: prefix("\xff\x0a", "\x00\x00\x00\x0cJXL \r\n\x87\n")},
+	{format: "jpeg 2000", match: prefix("\x00\x00\x00\x0cjP  \r\n\x87\n", "\xff\x4f\xff\x51")},
+	{format: "photoshop", match: prefix("8BPS")},
+	{format: "ico", match: prefix("\x00\x00\x01\x00")},
+	{format: "qoi", match: prefix("qoif")},
+}
+
+// DetectImageFormat identifies the image format from the first bytes of the data, or returns "" if it is not a known image format.
+// Some of the formats that are identified cannot be decoded.
+func DetectImageFormat(header []byte) string {
+	for _, signature := range imageSignatures {
+		if signature.match(header) {
+			return signature.format
+		}
+	}
+	return ""
+}
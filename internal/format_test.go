@@ -0,0 +1,147 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// A 1x1 lossless WebP image
+var webpImage, _ = base64.StdEncoding.DecodeString("UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+// encodeMultiPageTIFF makes an uncompressed, little-endian grayscale TIFF with one page of each size.
+func encodeMultiPageTIFF(sizes ...image.Point) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(8))
+	for i, size := range sizes {
+		const entries = 9
+		ifdEnd := buf.Len() + 2 + entries*12 + 4
+		pixels := size.X * size.Y
+		next := uint32(0)
+		if i < len(sizes)-1 {
+			next = uint32(ifdEnd + pixels)
+		}
+
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(entries))
+		for _, entry := range [][3]uint32{
+			{256, 4, uint32(size.X)}, // ImageWidth
+			{257, 4, uint32(size.Y)}, // ImageLength
+			{258, 3, 8},              // BitsPerSample
+			{259, 3, 1},              // Compression: none
+			{262, 3, 1},              // PhotometricInterpretation: black is zero
+			{273, 4, uint32(ifdEnd)}, // StripOffsets
+			{277, 3, 1},              // SamplesPerPixel
+			{278, 4, uint32(size.Y)}, // RowsPerStrip
+			{279, 4, uint32(pixels)}, // StripByteCounts
+		} {
+			_ = binary.Write(&buf, binary.LittleEndian, uint16(entry[0]))
+			_ = binary.Write(&buf, binary.LittleEndian, uint16(entry[1]))
+			_ = binary.Write(&buf, binary.LittleEndian, uint32(1))
+			if entry[1] == 3 {
+				_ = binary.Write(&buf, binary.LittleEndian, [2]uint16{uint16(entry[2]), 0})
+			} else {
+				_ = binary.Write(&buf, binary.LittleEndian, entry[2])
+			}
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, next)
+		buf.Write(make([]byte, pixels))
+	}
+	return buf.Bytes()
+}
+
+var _ = Describe("DecodeImage formats", func() {
+	It("decodes WebP images", func() {
+		im, err := internal.DecodeImage(bytes.NewReader(webpImage), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 1, 1)))
+	})
+
+	It("decodes BMP images", func() {
+		var buf bytes.Buffer
+		Expect(bmp.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 12, 8)))).To(Succeed())
+		im, err := internal.DecodeImage(&buf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 12, 8)))
+	})
+
+	It("decodes TIFF images", func() {
+		var buf bytes.Buffer
+		Expect(tiff.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 12, 8)), nil)).To(Succeed())
+		im, err := internal.DecodeImage(&buf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 12, 8)))
+	})
+
+	Context("multi-page TIFF images", func() {
+		var data []byte
+
+		BeforeEach(func() {
+			data = encodeMultiPageTIFF(image.Pt(10, 20), image.Pt(30, 40), image.Pt(50, 60))
+		})
+
+		It("decodes the first page by default", func() {
+			im, err := internal.DecodeImage(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 10, 20)))
+		})
+
+		It("decodes the selected page", func() {
+			im, err := internal.DecodeImage(bytes.NewReader(data), &internal.DecodeOptions{TiffPage: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 50, 60)))
+		})
+
+		It("checks the pixel limit against the selected page", func() {
+			_, err := internal.DecodeImage(bytes.NewReader(data), &internal.DecodeOptions{TiffPage: 1, MaxPixels: 1000})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("image exceeds maxPixels: 30x40 is 1200 pixels, more than the limit of 1000"))
+		})
+
+		It("returns an error when the page does not exist", func() {
+			_, err := internal.DecodeImage(bytes.NewReader(data), &internal.DecodeOptions{TiffPage: 3})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("page 3 is out of range, the TIFF has 3 pages"))
+		})
+	})
+
+	When("the image is in a format that cannot be decoded", func() {
+		It("returns an error with the detected format", func() {
+			heic := append([]byte("\x00\x00\x00\x18ftypheic"), make([]byte, 64)...)
+			_, err := internal.DecodeImage(bytes.NewReader(heic), nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("unsupported image format: heic"))
+
+			var formatErr *internal.UnsupportedFormatError
+			Expect(errors.As(err, &formatErr)).To(BeTrue())
+			Expect(formatErr.Format).To(Equal("heic"))
+		})
+	})
+
+	When("the data is not an image", func() {
+		It("returns an error", func() {
+			_, err := internal.DecodeImage(bytes.NewReader([]byte("hello world")), nil)
+			Expect(err).To(MatchError(image.ErrFormat))
+		})
+	})
+})
+
+var _ = Describe("DetectImageFormat", func() {
+	It("identifies image formats from their first bytes", func() {
+		Expect(internal.DetectImageFormat(webpImage)).To(Equal("webp"))
+		Expect(internal.DetectImageFormat([]byte("BM\x00\x00"))).To(Equal("bmp"))
+		Expect(internal.DetectImageFormat([]byte("MM\x00*\x00\x00\x00\x08"))).To(Equal("tiff"))
+		Expect(internal.DetectImageFormat([]byte("\x00\x00\x00\x1cftypavif"))).To(Equal("avif"))
+		Expect(internal.DetectImageFormat([]byte("<?xml version=\"1.0\"?>\n<svg></svg>"))).To(Equal("svg"))
+		Expect(internal.DetectImageFormat([]byte("<html><body><svg></svg></body></html>"))).To(Equal(""))
+	})
+})
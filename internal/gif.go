@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	GifFrameLast   = "last"
+	GifFrameMiddle = "middle"
+	GifFrameByTime = "by-time"
+)
+
+// ValidateGifFrame checks that the frame is an index, "last", "middle" or "by-time".
+func ValidateGifFrame(frame string) error {
+	switch frame {
+	case "", GifFrameLast, GifFrameMiddle, GifFrameByTime:
+		return nil
+	}
+	if _, err := strconv.Atoi(frame); err != nil {
+		return fmt.Errorf("invalid frame \"%s\", must be a frame number, %s, %s or %s", frame, GifFrameLast, GifFrameMiddle, GifFrameByTime)
+	}
+	return nil
+}
+
+// decodeGifFrame decodes every frame of an animated GIF and returns the selected frame, drawn on top of the frames before it.
+// Every frame is kept in memory while decoding, so the pixels of all of the frames are checked against the limit first.
+func decodeGifFrame(r io.Reader, options *DecodeOptions, maxPixels int64) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGifPixels(data, maxPixels); err != nil {
+		return nil, err
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	index, err := selectGifFrame(g, options.GifFrame, options.GifInterval)
+	if err != nil {
+		return nil, err
+	}
+	return composeGifFrame(g, index), nil
+}
+
+// checkGifPixels adds up the size of every frame in the GIF, without decompressing them, and checks the total against the limit.
+// A GIF that cannot be read is left for the decoder to report.
+func checkGifPixels(data []byte, maxPixels int64) error {
+	const headerLength = 13
+	if len(data) < headerLength {
+		return nil
+	}
+	width, height := int(binary.LittleEndian.Uint16(data[6:8])), int(binary.LittleEndian.Uint16(data[8:10]))
+	offset := headerLength + colorTableLength(data[10])
+
+	frames := 0
+	var pixels int64
+	for offset < len(data) {
+		switch data[offset] {
+		case 0x21:
+			// Extension: a label, then data sub-blocks
+			offset = skipGifSubBlocks(data, offset+2)
+		case 0x2c:
+			// Image descriptor, then an optional color table, the LZW code size and the image data sub-blocks
+			if offset+10 > len(data) {
+				return nil
+			}
+			frames++
+			pixels += int64(binary.LittleEndian.Uint16(data[offset+5:offset+7])) * int64(binary.LittleEndian.Uint16(data[offset+7:offset+9]))
+			if pixels > maxPixels {
+				return &LimitError{Limit: LimitMaxPixels, Max: maxPixels, Width: width, Height: height, Frames: frames, Pixels: pixels}
+			}
+			offset = skipGifSubBlocks(data, offset+10+colorTableLength(data[offset+9])+1)
+		default:
+			// The trailer, or data that the decoder will reject
+			return nil
+		}
+	}
+	return nil
+}
+
+// colorTableLength returns the size of the color table described by the flags of a GIF screen or image descriptor.
+func colorTableLength(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << (flags&0x07 + 1)
+}
+
+// skipGifSubBlocks returns the offset after the sub-blocks starting at offset, which end with an empty block.
+func skipGifSubBlocks(data []byte, offset int) int {
+	for offset < len(data) {
+		length := int(data[offset])
+		offset += 1 + length
+		if length == 0 {
+			break
+		}
+	}
+	return offset
+}
+
+func selectGifFrame(g *gif.GIF, frame string, interval time.Duration) (int, error) {
+	count := len(g.Image)
+	switch frame {
+	case GifFrameLast:
+		return count - 1, nil
+	case GifFrameMiddle:
+		return count / 2, nil
+	case GifFrameByTime:
+		// Stepping by a fixed interval means runs that far apart show successive frames, wherever the clock is within a frame's delay
+		if interval <= 0 {
+			return 0, fmt.Errorf("an interval is needed to choose the frame by time")
+		}
+		return int((Now().UnixNano() / int64(interval)) % int64(count)), nil
+	}
+
+	index, err := strconv.Atoi(frame)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frame \"%s\"", frame)
+	}
+	if index < 0 {
+		index += count
+	}
+	if index < 0 || index >= count {
+		return 0, fmt.Errorf("frame %s is out of range, the GIF has %d frames", frame, count)
+	}
+	return index, nil
+}
+
+// composeGifFrame draws the frames of the GIF up to the given index, applying the disposal method of each frame before drawing the next.
+func composeGifFrame(g *gif.GIF, index int) image.Image {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	for _, frame := range g.Image {
+		bounds = bounds.Union(frame.Bounds())
+	}
+
+	canvas := image.NewRGBA(bounds)
+	for i, frame := range g.Image[:index+1] {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious && i < index {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if i == index {
+			break
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return canvas
+}
@@ -0,0 +1,210 @@
+package internal_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var (
+	gifRed   = color.RGBA{R: 255, A: 255}
+	gifGreen = color.RGBA{G: 255, A: 255}
+	gifBlue  = color.RGBA{B: 255, A: 255}
+	gifClear = color.RGBA{}
+)
+
+func gifFrame(rect image.Rectangle, c color.Color) *image.Paletted {
+	frame := image.NewPaletted(rect, color.Palette{gifClear, gifRed, gifGreen, gifBlue})
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			frame.Set(x, y, c)
+		}
+	}
+	return frame
+}
+
+// encodeAnimatedGIF makes a 4x4 animation: a red frame, then a blue square in the top left, then a green square in the bottom right.
+func encodeAnimatedGIF(secondFrameDisposal byte) []byte {
+	var buf bytes.Buffer
+	Expect(gif.EncodeAll(&buf, &gif.GIF{
+		Image: []*image.Paletted{
+			gifFrame(image.Rect(0, 0, 4, 4), gifRed),
+			gifFrame(image.Rect(0, 0, 2, 2), gifBlue),
+			gifFrame(image.Rect(2, 2, 4, 4), gifGreen),
+		},
+		Delay:    []int{10, 20, 30},
+		Disposal: []byte{gif.DisposalNone, secondFrameDisposal, gif.DisposalNone},
+		Config:   image.Config{Width: 4, Height: 4},
+	})).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("DecodeImage with an animated GIF", func() {
+	var (
+		data    []byte
+		options *internal.DecodeOptions
+	)
+
+	BeforeEach(func() {
+		data = encodeAnimatedGIF(gif.DisposalNone)
+		options = &internal.DecodeOptions{}
+	})
+
+	decode := func() image.Image {
+		im, err := internal.DecodeImage(bytes.NewReader(data), options)
+		Expect(err).ToNot(HaveOccurred())
+		return im
+	}
+
+	colorAt := func(im image.Image, x, y int) color.RGBA {
+		return color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+	}
+
+	It("uses the first frame by default", func() {
+		im := decode()
+		Expect(colorAt(im, 0, 0)).To(Equal(gifRed))
+		Expect(colorAt(im, 3, 3)).To(Equal(gifRed))
+	})
+
+	It("draws the selected frame over the frames before it", func() {
+		options.GifFrame = "1"
+		im := decode()
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 4, 4)))
+		Expect(colorAt(im, 0, 0)).To(Equal(gifBlue))
+		Expect(colorAt(im, 3, 3)).To(Equal(gifRed))
+	})
+
+	It("selects the last frame", func() {
+		options.GifFrame = internal.GifFrameLast
+		im := decode()
+		Expect(colorAt(im, 0, 0)).To(Equal(gifBlue))
+		Expect(colorAt(im, 3, 0)).To(Equal(gifRed))
+		Expect(colorAt(im, 3, 3)).To(Equal(gifGreen))
+	})
+
+	It("selects the middle frame", func() {
+		options.GifFrame = internal.GifFrameMiddle
+		im := decode()
+		Expect(colorAt(im, 0, 0)).To(Equal(gifBlue))
+		Expect(colorAt(im, 3, 3)).To(Equal(gifRed))
+	})
+
+	It("counts negative frames back from the end", func() {
+		options.GifFrame = "-3"
+		im := decode()
+		Expect(colorAt(im, 0, 0)).To(Equal(gifRed))
+	})
+
+	When("a frame is disposed to the background", func() {
+		BeforeEach(func() {
+			data = encodeAnimatedGIF(gif.DisposalBackground)
+		})
+
+		It("clears that frame's area before drawing the next frame", func() {
+			options.GifFrame = internal.GifFrameLast
+			im := decode()
+			Expect(colorAt(im, 0, 0)).To(Equal(gifClear))
+			Expect(colorAt(im, 3, 0)).To(Equal(gifRed))
+			Expect(colorAt(im, 3, 3)).To(Equal(gifGreen))
+		})
+	})
+
+	When("a frame is disposed to the previous frame", func() {
+		BeforeEach(func() {
+			data = encodeAnimatedGIF(gif.DisposalPrevious)
+		})
+
+		It("restores the previous image before drawing the next frame", func() {
+			options.GifFrame = internal.GifFrameLast
+			im := decode()
+			Expect(colorAt(im, 0, 0)).To(Equal(gifRed))
+			Expect(colorAt(im, 3, 3)).To(Equal(gifGreen))
+		})
+	})
+
+	When("selecting the frame by time", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			options.GifFrame = internal.GifFrameByTime
+			internal.Now = func() time.Time { return now }
+			DeferCleanup(func() { internal.Now = time.Now })
+		})
+
+		It("changes frame every interval", func() {
+			options.GifInterval = time.Hour
+			now = time.Unix(0, 0).Add(3*time.Hour + time.Minute)
+			Expect(colorAt(decode(), 0, 0)).To(Equal(gifRed))
+			now = now.Add(time.Hour)
+			Expect(colorAt(decode(), 0, 0)).To(Equal(gifBlue))
+			Expect(colorAt(decode(), 3, 3)).To(Equal(gifRed))
+			now = now.Add(time.Hour)
+			Expect(colorAt(decode(), 3, 3)).To(Equal(gifGreen))
+		})
+
+		It("shows a different frame on each run when runs are one interval apart", func() {
+			options.GifInterval = time.Minute
+			now = time.Date(2026, 10, 18, 7, 0, 42, 0, time.UTC)
+			frames := [][2]color.Color{}
+			for i := 0; i < 4; i++ {
+				im := decode()
+				frames = append(frames, [2]color.Color{colorAt(im, 0, 0), colorAt(im, 3, 3)})
+				now = now.Add(time.Minute)
+			}
+
+			Expect(frames[:3]).To(ConsistOf(
+				[2]color.Color{gifRed, gifRed},
+				[2]color.Color{gifBlue, gifRed},
+				[2]color.Color{gifBlue, gifGreen},
+			))
+			Expect(frames[3]).To(Equal(frames[0]))
+		})
+
+		It("returns an error when there is no interval", func() {
+			now = time.Unix(0, 0)
+			_, err := internal.DecodeImage(bytes.NewReader(data), options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("an interval is needed to choose the frame by time"))
+		})
+	})
+
+	When("the frames are more than the pixel limit in total", func() {
+		It("returns an error before decoding them", func() {
+			options.GifFrame = internal.GifFrameLast
+			options.MaxPixels = 20
+			_, err := internal.DecodeImage(bytes.NewReader(data), options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("image exceeds maxPixels: the first 3 frames of the 4x4 animation are 24 pixels, more than the limit of 20"))
+		})
+	})
+
+	When("the frame is out of range", func() {
+		It("returns an error", func() {
+			options.GifFrame = "3"
+			_, err := internal.DecodeImage(bytes.NewReader(data), options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("frame 3 is out of range, the GIF has 3 frames"))
+		})
+	})
+})
+
+var _ = Describe("ValidateGifFrame", func() {
+	It("accepts frame numbers and names", func() {
+		for _, frame := range []string{"", "0", "12", "-1", "last", "middle", "by-time"} {
+			Expect(internal.ValidateGifFrame(frame)).To(Succeed())
+		}
+	})
+
+	It("rejects anything else", func() {
+		err := internal.ValidateGifFrame("first")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("invalid frame \"first\", must be a frame number, last, middle or by-time"))
+	})
+})
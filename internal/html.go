@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultMinPageImageSize is the smallest width and height, in pixels, of an <img> on a page that will be used.
+// Smaller images are usually icons, logos and tracking pixels.
+const DefaultMinPageImageSize = 200
+
+// metaImageProperties are the <meta> tags that name the image for a page, in order of preference.
+var metaImageProperties = []string{
+	"og:image:secure_url",
+	"og:image:url",
+	"og:image",
+	"twitter:image",
+	"twitter:image:src",
+}
+
+type PageImage struct {
+	URL string
+	// Width and Height are the size declared in the <img> tag, or 0 if it was not declared.
+	Width  int
+	Height int
+}
+
+// Page is what was found in an HTML page that can be used to find its image.
+type Page struct {
+	Title      string
+	MetaImages []string
+	Images     []PageImage
+}
+
+type NoPageImageError struct {
+	Page string
+}
+
+func (e *NoPageImageError) Error() string {
+	return fmt.Sprintf("no usable image found on page: %s", e.Page)
+}
+
+// ParsePage finds the og:image and twitter:image <meta> tags, and the <img> tags, in an HTML page.
+// Relative URLs are resolved against the page's location, or its <base> tag.
+func ParsePage(data []byte, location string) (*Page, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	base := location
+	var ogTitle, title string
+	metaImages := map[string]string{}
+	page := &Page{}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.DataAtom {
+			case atom.Base:
+				if href := attribute(node, "href"); href != "" && base == location {
+					base = ResolveURL(location, href)
+				}
+			case atom.Title:
+				if title == "" && node.FirstChild != nil {
+					title = strings.TrimSpace(node.FirstChild.Data)
+				}
+			case atom.Meta:
+				name := strings.ToLower(attribute(node, "property"))
+				if name == "" {
+					name = strings.ToLower(attribute(node, "name"))
+				}
+				content := strings.TrimSpace(attribute(node, "content"))
+				if name == "og:title" && ogTitle == "" {
+					ogTitle = content
+				}
+				if _, found := metaImages[name]; !found && content != "" {
+					metaImages[name] = content
+				}
+			case atom.Img:
+				if src := strings.TrimSpace(attribute(node, "src")); src != "" {
+					page.Images = append(page.Images, PageImage{
+						URL:    src,
+						Width:  dimension(attribute(node, "width")),
+						Height: dimension(attribute(node, "height")),
+					})
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	page.Title = ogTitle
+	if page.Title == "" {
+		page.Title = title
+	}
+	for _, property := range metaImageProperties {
+		image, found := metaImages[property]
+		if !found {
+			continue
+		}
+		image = ResolveURL(base, image)
+		if !slices.Contains(page.MetaImages, image) {
+			page.MetaImages = append(page.MetaImages, image)
+		}
+	}
+	for i := range page.Images {
+		page.Images[i].URL = ResolveURL(base, page.Images[i].URL)
+	}
+	return page, nil
+}
+
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// dimension parses an <img> width or height, which may be written with a "px" suffix.
+func dimension(value string) int {
+	size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
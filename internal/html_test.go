@@ -0,0 +1,51 @@
+package internal_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ParsePage", func() {
+	It("finds the images on the page", func() {
+		page, err := internal.ParsePage([]byte(`<!DOCTYPE html>
+<html>
+<head>
+  <title>Daily comic - Example</title>
+  <meta property="og:title" content="Episode 12">
+  <meta name="twitter:image" content="/cards/12.png">
+  <meta property="og:image" content="https://cdn.example.com/comics/12.png">
+</head>
+<body>
+  <img src="/logo.png" width="64" height="64">
+  <img src="strips/12.png" width="800px">
+</body>
+</html>`), "https://comics.example.com/today/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(page).To(Equal(&internal.Page{
+			Title: "Episode 12",
+			MetaImages: []string{
+				"https://cdn.example.com/comics/12.png",
+				"https://comics.example.com/cards/12.png",
+			},
+			Images: []internal.PageImage{
+				{URL: "https://comics.example.com/logo.png", Width: 64, Height: 64},
+				{URL: "https://comics.example.com/today/strips/12.png", Width: 800},
+			},
+		}))
+	})
+
+	It("resolves relative URLs against the base tag", func() {
+		page, err := internal.ParsePage([]byte(`<html><head>
+<title>Webcam</title>
+<base href="https://static.example.com/cams/">
+</head><body><img src="front-door.jpg"></body></html>`), "https://example.com/webcam")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(page.Title).To(Equal("Webcam"))
+		Expect(page.MetaImages).To(BeEmpty())
+		Expect(page.Images).To(Equal([]internal.PageImage{
+			{URL: "https://static.example.com/cams/front-door.jpg"},
+		}))
+	})
+})
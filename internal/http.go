@@ -1,10 +1,167 @@
 package internal
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
+const (
+	SchemeHttp  = "http"
+	SchemeHttps = "https"
+	SchemeFile  = "file"
+	SchemeData  = "data"
+)
+
+type HttpOptions struct {
+	Headers     map[string]string
+	UserAgent   string
+	Username    string
+	Password    string
+	BearerToken string
+
+	Timeout         time.Duration
+	ConnectTimeout  time.Duration
+	Retries         int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	CAFile             string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+	Proxy              string
+
+	Cache *CacheOptions
+	S3    *S3Options
+
+	// sign is set for requests that must be signed, such as those to S3, and is called for every attempt.
+	sign func(req *http.Request)
+}
+
+// withDefaults returns a copy of the options, with defaults filled in for any unset timeouts.
+// Nil options also get the default number of retries.
+func (o *HttpOptions) withDefaults() *HttpOptions {
+	options := &HttpOptions{Retries: DefaultRetries}
+	if o != nil {
+		*options = *o
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.ConnectTimeout == 0 {
+		options.ConnectTimeout = DefaultConnectTimeout
+	}
+	if options.RetryBackoff == 0 {
+		options.RetryBackoff = DefaultRetryBackoff
+	}
+	if options.MaxRetryBackoff == 0 {
+		options.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+	return options
+}
+
+func (o *HttpOptions) client() (*http.Client, error) {
+	dialer := &net.Dialer{Timeout: o.ConnectTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = o.ConnectTimeout
+	if err := o.configureTransport(transport); err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   o.Timeout,
+	}, nil
+}
+
+func (o *HttpOptions) apply(req *http.Request) {
+	if o == nil {
+		return
+	}
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	if o.UserAgent != "" {
+		req.Header.Set("User-Agent", o.UserAgent)
+	}
+	if o.Username != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	if o.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	if o.sign != nil {
+		o.sign(req)
+	}
+}
+
 //counterfeiter:generate . HttpGetter
-type HttpGetter func(path string) (*http.Response, error)
+type HttpGetter func(path string, options *HttpOptions) (*http.Response, error)
+
+type fetcher func(source string, options *HttpOptions) (*http.Response, error)
+
+var fetchers = map[string]fetcher{
+	SchemeHttp:  getHttp,
+	SchemeHttps: getHttp,
+	SchemeFile:  getFile,
+	SchemeData:  getData,
+	SchemeS3:    getS3,
+}
+
+// SourceScheme returns the scheme used to fetch an image source.
+// Sources without a scheme are treated as local file paths.
+func SourceScheme(source string) string {
+	if strings.HasPrefix(strings.ToLower(source), SchemeData+":") {
+		return SchemeData
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || len(u.Scheme) <= 1 {
+		return SchemeFile
+	}
+	return strings.ToLower(u.Scheme)
+}
+
+func ValidateSource(source string) error {
+	scheme := SourceScheme(source)
+	if _, ok := fetchers[scheme]; !ok {
+		return fmt.Errorf("unsupported image source scheme: \"%s\"", scheme)
+	}
+	switch scheme {
+	case SchemeFile:
+		_, err := FilePath(source)
+		return err
+	case SchemeData:
+		_, err := ParseDataURI(source)
+		return err
+	case SchemeS3:
+		_, err := ParseS3Source(source)
+		return err
+	}
+	return nil
+}
+
+var HttpGet HttpGetter = func(source string, options *HttpOptions) (*http.Response, error) {
+	scheme := SourceScheme(source)
+	fetch, ok := fetchers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported image source scheme: \"%s\"", scheme)
+	}
+	return fetch(source, options)
+}
 
-var HttpGet = http.Get
+func getHttp(source string, options *HttpOptions) (*http.Response, error) {
+	options = options.withDefaults()
+	client, err := options.client()
+	if err != nil {
+		return nil, err
+	}
+	if options.Cache != nil {
+		return getCached(client, source, options)
+	}
+	return doWithRetries(client, source, options, nil)
+}
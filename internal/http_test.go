@@ -0,0 +1,231 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("HttpGet", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+	)
+
+	BeforeEach(func() {
+		requests = []*http.Request{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			_, _ = w.Write([]byte("image data"))
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("fetches the source", func() {
+		res, err := internal.HttpGet(server.URL+"/image.jpg", nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].URL.Path).To(Equal("/image.jpg"))
+	})
+
+	It("sends custom headers and a user agent", func() {
+		res, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+			Headers:   map[string]string{"X-Api-Key": "secret"},
+			UserAgent: "eink-radiator",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+		Expect(requests[0].Header.Get("X-Api-Key")).To(Equal("secret"))
+		Expect(requests[0].Header.Get("User-Agent")).To(Equal("eink-radiator"))
+	})
+
+	It("sends basic auth credentials", func() {
+		res, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+			Username: "link",
+			Password: "triforce",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+		username, password, ok := requests[0].BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("link"))
+		Expect(password).To(Equal("triforce"))
+	})
+
+	It("sends a bearer token", func() {
+		res, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+			BearerToken: "abc123",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Body.Close()).To(Succeed())
+		Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer abc123"))
+	})
+})
+
+var _ = Describe("Retrying requests", func() {
+	var (
+		server    *httptest.Server
+		responses []int
+		requests  int
+		sleeps    []time.Duration
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		sleeps = []time.Duration{}
+		internal.Sleep = func(d time.Duration) {
+			sleeps = append(sleeps, d)
+		}
+		internal.Logger = log.New(GinkgoWriter, "", 0)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := responses[min(requests, len(responses)-1)]
+			requests += 1
+			w.WriteHeader(status)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	When("the server has a transient failure", func() {
+		BeforeEach(func() {
+			responses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+		})
+
+		It("retries with an increasing backoff", func() {
+			res, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+				Retries:         2,
+				RetryBackoff:    100 * time.Millisecond,
+				MaxRetryBackoff: time.Second,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Body.Close()).To(Succeed())
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(requests).To(Equal(3))
+
+			Expect(sleeps).To(HaveLen(2))
+			Expect(sleeps[0]).To(BeNumerically("~", 75*time.Millisecond, 25*time.Millisecond))
+			Expect(sleeps[1]).To(BeNumerically("~", 150*time.Millisecond, 50*time.Millisecond))
+		})
+	})
+
+	When("the server keeps failing", func() {
+		BeforeEach(func() {
+			responses = []int{http.StatusBadGateway}
+		})
+
+		It("gives up and reports the number of attempts", func() {
+			_, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+				Retries: 3,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(Equal(4))
+			Expect(err.Error()).To(Equal("request failed after 4 attempts: server responded with 502 Bad Gateway"))
+
+			var retryErr *internal.RetryError
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(retryErr.Attempts).To(Equal(4))
+		})
+	})
+
+	When("the server responds with a client error", func() {
+		BeforeEach(func() {
+			responses = []int{http.StatusNotFound}
+		})
+
+		It("does not retry", func() {
+			res, err := internal.HttpGet(server.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Body.Close()).To(Succeed())
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(requests).To(Equal(1))
+		})
+	})
+
+	When("the server does not respond in time", func() {
+		BeforeEach(func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests += 1
+				time.Sleep(200 * time.Millisecond)
+			})
+		})
+
+		It("times out and retries", func() {
+			_, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+				Timeout: 20 * time.Millisecond,
+				Retries: 1,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("request failed after 2 attempts: "))
+			Expect(err.Error()).To(ContainSubstring("Client.Timeout exceeded"))
+			Expect(requests).To(Equal(2))
+		})
+	})
+
+	When("the server cannot be reached", func() {
+		It("retries", func() {
+			server.Close()
+			_, err := internal.HttpGet(server.URL, &internal.HttpOptions{
+				Retries: 1,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("request failed after 2 attempts: "))
+			Expect(err.Error()).To(ContainSubstring("connection refused"))
+		})
+	})
+
+	When("the URL has credentials and a query string", func() {
+		BeforeEach(func() {
+			responses = []int{http.StatusServiceUnavailable, http.StatusOK}
+		})
+
+		It("leaves them out of the log", func() {
+			var logged bytes.Buffer
+			internal.Logger = log.New(&logged, "", 0)
+			source := strings.Replace(server.URL, "http://", "http://user:hunter2@", 1) + "/image.png?api_key=secret"
+
+			res, err := internal.HttpGet(source, &internal.HttpOptions{Retries: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Body.Close()).To(Succeed())
+
+			redacted := strings.Replace(server.URL, "http://", "http://user:xxxxx@", 1) + "/image.png"
+			Expect(logged.String()).To(ContainSubstring("fetching " + redacted + " (attempt 1 of 2)"))
+			Expect(logged.String()).To(ContainSubstring("attempt 1 of 2 for " + redacted + " failed"))
+			Expect(logged.String()).ToNot(ContainSubstring("hunter2"))
+			Expect(logged.String()).ToNot(ContainSubstring("secret"))
+		})
+	})
+
+	When("the server cannot be reached with credentials in the URL", func() {
+		It("leaves them out of the error", func() {
+			server.Close()
+			_, err := internal.HttpGet(server.URL+"/image.png?api_key=secret", &internal.HttpOptions{Retries: 0})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(server.URL + "/image.png"))
+			Expect(err.Error()).ToNot(ContainSubstring("secret"))
+		})
+	})
+
+	When("the request is not valid", func() {
+		It("does not retry", func() {
+			_, err := internal.HttpGet("http://[::1", &internal.HttpOptions{
+				Retries: 1,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(sleeps).To(BeEmpty())
+		})
+	})
+})
@@ -1,40 +1,129 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	"image/png"
 	"io"
 	"os"
+	"time"
 
+	_ "golang.org/x/image/bmp"
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
+type DecodeOptions struct {
+	MaxPixels int64
+	// GifFrame selects the frame of an animated GIF: an index, "last", "middle" or "by-time". If empty, the first frame is used.
+	GifFrame string
+	// GifInterval is how long each frame is shown when GifFrame is "by-time", and must be set to use it.
+	GifInterval time.Duration
+	// TiffPage selects the page of a multi-page TIFF, counting from 0.
+	TiffPage int
+	// Width and Height are the size of the image being generated. SVG images are drawn to fit this size, rather than being scaled afterwards.
+	Width, Height int
+	// Fit is how SVG images are fitted to the size: SvgFitResize, SvgFitContain or SvgFitCover.
+	Fit string
+	// IgnoreOrientation turns off rotating JPEG images according to their EXIF orientation.
+	IgnoreOrientation bool
+}
+
 //counterfeiter:generate . ImageDecoder
-type ImageDecoder func(r io.Reader) (image.Image, error)
+type ImageDecoder func(r io.Reader, options *DecodeOptions) (image.Image, error)
+
+// DecodeImage checks the image dimensions against the pixel limit before decoding the full image.
+var DecodeImage ImageDecoder = func(r io.Reader, options *DecodeOptions) (image.Image, error) {
+	var maxPixels int64 = DefaultMaxPixels
+	if options != nil && options.MaxPixels > 0 {
+		maxPixels = options.MaxPixels
+	}
+
+	buffered := bufio.NewReaderSize(r, xmlSniffLength)
+	if start, _ := sniff(buffered); IsSvg(start) {
+		return decodeSvg(buffered, options, maxPixels)
+	}
+	r = buffered
+
+	var header bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if errors.Is(err, image.ErrFormat) {
+		if detected := DetectImageFormat(header.Bytes()); detected != "" {
+			return nil, &UnsupportedFormatError{Format: detected}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == "tiff" && options != nil && options.TiffPage > 0 {
+		return decodeTiffPage(io.MultiReader(&header, r), options.TiffPage, maxPixels)
+	}
+	if err := checkPixels(config, maxPixels); err != nil {
+		return nil, err
+	}
+
+	if format == "gif" && options != nil && options.GifFrame != "" {
+		return decodeGifFrame(io.MultiReader(&header, r), options, maxPixels)
+	}
+
+	orientation := 1
+	if format == "jpeg" && (options == nil || !options.IgnoreOrientation) {
+		orientation = jpegOrientation(header.Bytes())
+	}
 
-var DecodeImage ImageDecoder = func(r io.Reader) (image.Image, error) {
-	im, _, err := image.Decode(r)
-	return im, err
+	im, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, err
+	}
+	return applyOrientation(im, orientation), nil
 }
 
+func checkPixels(config image.Config, maxPixels int64) error {
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return &LimitError{Limit: LimitMaxPixels, Max: maxPixels, Width: config.Width, Height: config.Height}
+	}
+	return nil
+}
+
+const (
+	FormatPNG    = "png"
+	FormatNetpbm = "netpbm"
+)
+
+// OutputFormats are the formats that images can be written in.
+var OutputFormats = []string{FormatPNG, FormatNetpbm}
+
 //counterfeiter:generate . ImageEncoder
-type ImageEncoder func(w io.Writer, i image.Image) error
+type ImageEncoder func(w io.Writer, i image.Image, format string) error
 
-var EncodeImage ImageEncoder = png.Encode
+// EncodeImage writes the image as a PNG, or as a Netpbm file (PBM, PGM or PPM, depending on the colors in the image).
+var EncodeImage ImageEncoder = func(w io.Writer, i image.Image, format string) error {
+	switch format {
+	case FormatPNG, "":
+		return png.Encode(w, i)
+	case FormatNetpbm:
+		return encodeNetpbm(w, i)
+	}
+	return fmt.Errorf("unknown output format: %s", format)
+}
 
 //counterfeiter:generate . ImageWriter
-type ImageWriter func(file string, i image.Image) error
+type ImageWriter func(file string, i image.Image, format string) error
 
-var WriteImage ImageWriter = func(file string, i image.Image) error {
+var WriteImage ImageWriter = func(file string, i image.Image, format string) error {
 	f, err := os.Create(file)
 	if err != nil {
 		return err
 	}
-	err = EncodeImage(f, i)
+	err = EncodeImage(f, i, format)
 	if err != nil {
 		return err
 	}
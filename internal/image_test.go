@@ -0,0 +1,56 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+func encodePNG(width, height int) []byte {
+	var buf bytes.Buffer
+	Expect(png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)))).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("DecodeImage", func() {
+	It("decodes the image", func() {
+		im, err := internal.DecodeImage(bytes.NewReader(encodePNG(40, 30)), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 40, 30)))
+	})
+
+	When("the image has more pixels than the limit", func() {
+		It("returns a limit error without decoding", func() {
+			_, err := internal.DecodeImage(bytes.NewReader(encodePNG(40, 30)), &internal.DecodeOptions{MaxPixels: 1000})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("image exceeds maxPixels: 40x30 is 1200 pixels, more than the limit of 1000"))
+
+			var limitErr *internal.LimitError
+			Expect(errors.As(err, &limitErr)).To(BeTrue())
+			Expect(limitErr.Limit).To(Equal(internal.LimitMaxPixels))
+		})
+	})
+})
+
+var _ = Describe("LimitReader", func() {
+	It("reads data up to the limit", func() {
+		var out bytes.Buffer
+		_, err := out.ReadFrom(internal.LimitReader(bytes.NewReader([]byte("0123456789")), 10))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.String()).To(Equal("0123456789"))
+	})
+
+	It("fails when there is more data than the limit", func() {
+		var out bytes.Buffer
+		_, err := out.ReadFrom(internal.LimitReader(bytes.NewReader([]byte("0123456789")), 9))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("image exceeds maxBytes: the image data is larger than the limit of 9 bytes"))
+		Expect(out.Len()).To(BeNumerically("<=", 9))
+	})
+})
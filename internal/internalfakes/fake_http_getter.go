@@ -9,10 +9,11 @@ import (
 )
 
 type FakeHttpGetter struct {
-	Stub        func(string) (*http.Response, error)
+	Stub        func(string, *internal.HttpOptions) (*http.Response, error)
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 string
+		arg2 *internal.HttpOptions
 	}
 	returns struct {
 		result1 *http.Response
@@ -26,18 +27,19 @@ type FakeHttpGetter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeHttpGetter) Spy(arg1 string) (*http.Response, error) {
+func (fake *FakeHttpGetter) Spy(arg1 string, arg2 *internal.HttpOptions) (*http.Response, error) {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
 		arg1 string
-	}{arg1})
+		arg2 *internal.HttpOptions
+	}{arg1, arg2})
 	stub := fake.Stub
 	returns := fake.returns
-	fake.recordInvocation("HttpGetter", []interface{}{arg1})
+	fake.recordInvocation("HttpGetter", []interface{}{arg1, arg2})
 	fake.mutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -51,16 +53,16 @@ func (fake *FakeHttpGetter) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeHttpGetter) Calls(stub func(string) (*http.Response, error)) {
+func (fake *FakeHttpGetter) Calls(stub func(string, *internal.HttpOptions) (*http.Response, error)) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
 }
 
-func (fake *FakeHttpGetter) ArgsForCall(i int) string {
+func (fake *FakeHttpGetter) ArgsForCall(i int) (string, *internal.HttpOptions) {
 	fake.mutex.RLock()
 	defer fake.mutex.RUnlock()
-	return fake.argsForCall[i].arg1
+	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
 }
 
 func (fake *FakeHttpGetter) Returns(result1 *http.Response, result2 error) {
@@ -10,10 +10,11 @@ import (
 )
 
 type FakeImageDecoder struct {
-	Stub        func(io.Reader) (image.Image, error)
+	Stub        func(io.Reader, *internal.DecodeOptions) (image.Image, error)
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 io.Reader
+		arg2 *internal.DecodeOptions
 	}
 	returns struct {
 		result1 image.Image
@@ -27,18 +28,19 @@ type FakeImageDecoder struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeImageDecoder) Spy(arg1 io.Reader) (image.Image, error) {
+func (fake *FakeImageDecoder) Spy(arg1 io.Reader, arg2 *internal.DecodeOptions) (image.Image, error) {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
 		arg1 io.Reader
-	}{arg1})
+		arg2 *internal.DecodeOptions
+	}{arg1, arg2})
 	stub := fake.Stub
 	returns := fake.returns
-	fake.recordInvocation("ImageDecoder", []interface{}{arg1})
+	fake.recordInvocation("ImageDecoder", []interface{}{arg1, arg2})
 	fake.mutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -52,16 +54,16 @@ func (fake *FakeImageDecoder) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeImageDecoder) Calls(stub func(io.Reader) (image.Image, error)) {
+func (fake *FakeImageDecoder) Calls(stub func(io.Reader, *internal.DecodeOptions) (image.Image, error)) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
 }
 
-func (fake *FakeImageDecoder) ArgsForCall(i int) io.Reader {
+func (fake *FakeImageDecoder) ArgsForCall(i int) (io.Reader, *internal.DecodeOptions) {
 	fake.mutex.RLock()
 	defer fake.mutex.RUnlock()
-	return fake.argsForCall[i].arg1
+	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
 }
 
 func (fake *FakeImageDecoder) Returns(result1 image.Image, result2 error) {
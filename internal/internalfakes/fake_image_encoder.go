@@ -10,11 +10,12 @@ import (
 )
 
 type FakeImageEncoder struct {
-	Stub        func(io.Writer, image.Image) error
+	Stub        func(io.Writer, image.Image, string) error
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 io.Writer
 		arg2 image.Image
+		arg3 string
 	}
 	returns struct {
 		result1 error
@@ -26,19 +27,20 @@ type FakeImageEncoder struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeImageEncoder) Spy(arg1 io.Writer, arg2 image.Image) error {
+func (fake *FakeImageEncoder) Spy(arg1 io.Writer, arg2 image.Image, arg3 string) error {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
 		arg1 io.Writer
 		arg2 image.Image
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.Stub
 	returns := fake.returns
-	fake.recordInvocation("ImageEncoder", []interface{}{arg1, arg2})
+	fake.recordInvocation("ImageEncoder", []interface{}{arg1, arg2, arg3})
 	fake.mutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -52,16 +54,16 @@ func (fake *FakeImageEncoder) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeImageEncoder) Calls(stub func(io.Writer, image.Image) error) {
+func (fake *FakeImageEncoder) Calls(stub func(io.Writer, image.Image, string) error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
 }
 
-func (fake *FakeImageEncoder) ArgsForCall(i int) (io.Writer, image.Image) {
+func (fake *FakeImageEncoder) ArgsForCall(i int) (io.Writer, image.Image, string) {
 	fake.mutex.RLock()
 	defer fake.mutex.RUnlock()
-	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
+	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2, fake.argsForCall[i].arg3
 }
 
 func (fake *FakeImageEncoder) Returns(result1 error) {
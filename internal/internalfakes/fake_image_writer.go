@@ -9,11 +9,12 @@ import (
 )
 
 type FakeImageWriter struct {
-	Stub        func(string, image.Image) error
+	Stub        func(string, image.Image, string) error
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 string
 		arg2 image.Image
+		arg3 string
 	}
 	returns struct {
 		result1 error
@@ -25,19 +26,20 @@ type FakeImageWriter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeImageWriter) Spy(arg1 string, arg2 image.Image) error {
+func (fake *FakeImageWriter) Spy(arg1 string, arg2 image.Image, arg3 string) error {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
 		arg1 string
 		arg2 image.Image
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.Stub
 	returns := fake.returns
-	fake.recordInvocation("ImageWriter", []interface{}{arg1, arg2})
+	fake.recordInvocation("ImageWriter", []interface{}{arg1, arg2, arg3})
 	fake.mutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -51,16 +53,16 @@ func (fake *FakeImageWriter) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeImageWriter) Calls(stub func(string, image.Image) error) {
+func (fake *FakeImageWriter) Calls(stub func(string, image.Image, string) error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
 }
 
-func (fake *FakeImageWriter) ArgsForCall(i int) (string, image.Image) {
+func (fake *FakeImageWriter) ArgsForCall(i int) (string, image.Image, string) {
 	fake.mutex.RLock()
 	defer fake.mutex.RUnlock()
-	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
+	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2, fake.argsForCall[i].arg3
 }
 
 func (fake *FakeImageWriter) Returns(result1 error) {
@@ -0,0 +1,123 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package internalfakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type FakeLastKnownGoodLoader struct {
+	Stub        func(string, string) ([]byte, time.Time, error)
+	mutex       sync.RWMutex
+	argsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	returns struct {
+		result1 []byte
+		result2 time.Time
+		result3 error
+	}
+	returnsOnCall map[int]struct {
+		result1 []byte
+		result2 time.Time
+		result3 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeLastKnownGoodLoader) Spy(arg1 string, arg2 string) ([]byte, time.Time, error) {
+	fake.mutex.Lock()
+	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
+	fake.argsForCall = append(fake.argsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.Stub
+	returns := fake.returns
+	fake.recordInvocation("LastKnownGoodLoader", []interface{}{arg1, arg2})
+	fake.mutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return returns.result1, returns.result2, returns.result3
+}
+
+func (fake *FakeLastKnownGoodLoader) CallCount() int {
+	fake.mutex.RLock()
+	defer fake.mutex.RUnlock()
+	return len(fake.argsForCall)
+}
+
+func (fake *FakeLastKnownGoodLoader) Calls(stub func(string, string) ([]byte, time.Time, error)) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.Stub = stub
+}
+
+func (fake *FakeLastKnownGoodLoader) ArgsForCall(i int) (string, string) {
+	fake.mutex.RLock()
+	defer fake.mutex.RUnlock()
+	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
+}
+
+func (fake *FakeLastKnownGoodLoader) Returns(result1 []byte, result2 time.Time, result3 error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.Stub = nil
+	fake.returns = struct {
+		result1 []byte
+		result2 time.Time
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeLastKnownGoodLoader) ReturnsOnCall(i int, result1 []byte, result2 time.Time, result3 error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.Stub = nil
+	if fake.returnsOnCall == nil {
+		fake.returnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 time.Time
+			result3 error
+		})
+	}
+	fake.returnsOnCall[i] = struct {
+		result1 []byte
+		result2 time.Time
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeLastKnownGoodLoader) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.mutex.RLock()
+	defer fake.mutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeLastKnownGoodLoader) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ internal.LastKnownGoodLoader = new(FakeLastKnownGoodLoader).Spy
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s jsonPathSegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return "." + s.key
+}
+
+// parseJSONPath splits a path such as data.items[0].url into its keys and array indexes.
+// Keys that contain dots or brackets can be quoted: data["image.url"].
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	segments := []jsonPathSegment{}
+	rest := path
+	expectKey := true
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "[\""):
+			end := strings.Index(rest[2:], "\"]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted key in path \"%s\"", path)
+			}
+			segments = append(segments, jsonPathSegment{key: rest[2 : 2+end]})
+			rest = rest[2+end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in path \"%s\"", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index \"%s\" in path \"%s\"", rest[1:end], path)
+			}
+			segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			if expectKey {
+				return nil, fmt.Errorf("empty key in path \"%s\"", path)
+			}
+			rest = rest[1:]
+			expectKey = true
+			continue
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("missing \".\" before \"%s\" in path \"%s\"", rest, path)
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path \"%s\"", path)
+			}
+			segments = append(segments, jsonPathSegment{key: rest[:end]})
+			rest = rest[end:]
+		}
+		expectKey = false
+	}
+	if expectKey {
+		return nil, fmt.Errorf("empty key in path \"%s\"", path)
+	}
+	return segments, nil
+}
+
+// ValidateJSONPath returns an error if the path is malformed.
+func ValidateJSONPath(path string) error {
+	_, err := parseJSONPath(path)
+	return err
+}
+
+// LookupJSONPath returns the string found at the path in the JSON document.
+// Negative indexes count back from the end of an array, so items[-1] is the last item.
+func LookupJSONPath(data []byte, path string) (string, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	location := ""
+	for _, segment := range segments {
+		if segment.isIndex {
+			array, ok := value.([]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s is not an array", describeJSONLocation(location))
+			}
+			index := segment.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index < 0 || index >= len(array) {
+				return "", fmt.Errorf("index %d is out of range at %s (length %d)", segment.index, describeJSONLocation(location), len(array))
+			}
+			value = array[index]
+		} else {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s is not an object", describeJSONLocation(location))
+			}
+			value, ok = object[segment.key]
+			if !ok {
+				return "", fmt.Errorf("key \"%s\" not found at %s", segment.key, describeJSONLocation(location))
+			}
+		}
+		location += segment.String()
+	}
+
+	result, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("value at %s is not a string", describeJSONLocation(location))
+	}
+	if result == "" {
+		return "", fmt.Errorf("value at %s is empty", describeJSONLocation(location))
+	}
+	return result, nil
+}
+
+func describeJSONLocation(location string) string {
+	if location == "" {
+		return "the top level"
+	}
+	return strings.TrimPrefix(location, ".")
+}
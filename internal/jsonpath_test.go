@@ -0,0 +1,69 @@
+package internal_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("LookupJSONPath", func() {
+	document := []byte(`{
+		"data": {
+			"items": [
+				{"url": "https://apod.example.com/first.jpg", "hdurl": ""},
+				{"url": "https://apod.example.com/second.jpg", "size": 1024}
+			],
+			"image.url": "https://apod.example.com/dotted.jpg"
+		}
+	}`)
+
+	DescribeTable("finding values",
+		func(path, expected string) {
+			value, err := internal.LookupJSONPath(document, path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(expected))
+		},
+		Entry("nested keys and indexes", "data.items[0].url", "https://apod.example.com/first.jpg"),
+		Entry("negative indexes", "data.items[-1].url", "https://apod.example.com/second.jpg"),
+		Entry("quoted keys", `data["image.url"]`, "https://apod.example.com/dotted.jpg"),
+	)
+
+	DescribeTable("reporting missing values",
+		func(path, expected string) {
+			_, err := internal.LookupJSONPath(document, path)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(expected))
+		},
+		Entry("missing key", "data.images[0].url", `key "images" not found at data`),
+		Entry("index out of range", "data.items[2].url", "index 2 is out of range at data.items (length 2)"),
+		Entry("not an array", "data[0]", "data is not an array"),
+		Entry("not an object", "data.items.url", "data.items is not an object"),
+		Entry("not a string", "data.items[1].size", "value at data.items[1].size is not a string"),
+		Entry("empty string", "data.items[0].hdurl", "value at data.items[0].hdurl is empty"),
+	)
+
+	When("the document is not JSON", func() {
+		It("returns an error", func() {
+			_, err := internal.LookupJSONPath([]byte("<html></html>"), "data.url")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("failed to parse JSON: "))
+		})
+	})
+})
+
+var _ = Describe("ValidateJSONPath", func() {
+	DescribeTable("invalid paths",
+		func(path, expected string) {
+			err := internal.ValidateJSONPath(path)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(expected))
+		},
+		Entry("empty", "", "path is empty"),
+		Entry("empty key", "data..url", `empty key in path "data..url"`),
+		Entry("trailing dot", "data.", `empty key in path "data."`),
+		Entry("bad index", "items[first]", `invalid index "first" in path "items[first]"`),
+		Entry("unterminated index", "items[0", `unterminated index in path "items[0"`),
+		Entry("missing dot", "items[0]url", `missing "." before "url" in path "items[0]url"`),
+	)
+})
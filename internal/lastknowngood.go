@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultLastKnownGoodDirectory returns the directory used for last known good images when one is not configured.
+func DefaultLastKnownGoodDirectory() string {
+	return filepath.Join(DefaultCacheDirectory(), "last-known-good")
+}
+
+func lastKnownGoodPath(directory, source string) string {
+	if directory == "" {
+		directory = DefaultLastKnownGoodDirectory()
+	}
+	hash := sha256.Sum256([]byte(source))
+	return filepath.Join(directory, hex.EncodeToString(hash[:]))
+}
+
+//counterfeiter:generate . LastKnownGoodSaver
+type LastKnownGoodSaver func(directory, source string, data []byte) error
+
+var SaveLastKnownGood LastKnownGoodSaver = func(directory, source string, data []byte) error {
+	path := lastKnownGoodPath(directory, source)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "image-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//counterfeiter:generate . LastKnownGoodLoader
+type LastKnownGoodLoader func(directory, source string) ([]byte, time.Time, error)
+
+var LoadLastKnownGood LastKnownGoodLoader = func(directory, source string) ([]byte, time.Time, error) {
+	path := lastKnownGoodPath(directory, source)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
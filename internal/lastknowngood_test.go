@@ -0,0 +1,36 @@
+package internal_test
+
+import (
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("Last known good images", func() {
+	var directory string
+
+	BeforeEach(func() {
+		directory = filepath.Join(GinkgoT().TempDir(), "last-known-good")
+	})
+
+	It("saves and loads the image data for a source", func() {
+		Expect(internal.SaveLastKnownGood(directory, "https://www.example.com/image.jpg", []byte("image data"))).To(Succeed())
+		Expect(internal.SaveLastKnownGood(directory, "https://www.example.com/other.jpg", []byte("other data"))).To(Succeed())
+
+		data, fetchedAt, err := internal.LoadLastKnownGood(directory, "https://www.example.com/image.jpg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("image data"))
+		Expect(fetchedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	When("there is no saved image", func() {
+		It("returns an error", func() {
+			_, _, err := internal.LoadLastKnownGood(directory, "https://www.example.com/image.jpg")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultMaxBytes  = 50 * 1024 * 1024
+	DefaultMaxPixels = 25 * 1000 * 1000
+
+	LimitMaxBytes  = "maxBytes"
+	LimitMaxPixels = "maxPixels"
+)
+
+type LimitError struct {
+	Limit  string
+	Max    int64
+	Width  int
+	Height int
+	// Frames and Pixels are set when the frames of an animation are more than the limit in total.
+	Frames int
+	Pixels int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LimitMaxPixels && e.Frames > 0 {
+		return fmt.Sprintf("image exceeds %s: the first %d frames of the %dx%d animation are %d pixels, more than the limit of %d", e.Limit, e.Frames, e.Width, e.Height, e.Pixels, e.Max)
+	}
+	if e.Limit == LimitMaxPixels {
+		return fmt.Sprintf("image exceeds %s: %dx%d is %d pixels, more than the limit of %d", e.Limit, e.Width, e.Height, int64(e.Width)*int64(e.Height), e.Max)
+	}
+	return fmt.Sprintf("image exceeds %s: the image data is larger than the limit of %d bytes", e.Limit, e.Max)
+}
+
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &LimitError{Limit: LimitMaxBytes, Max: l.max}
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), &LimitError{Limit: LimitMaxBytes, Max: l.max}
+	}
+	return n, err
+}
+
+// LimitReader returns a reader that fails with a LimitError once more than maxBytes have been read.
+func LimitReader(r io.Reader, maxBytes int64) io.Reader {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &limitedReader{r: r, remaining: maxBytes, max: maxBytes}
+}
+
+// CheckContentLength fails early if the source has declared that it is larger than maxBytes.
+func CheckContentLength(contentLength, maxBytes int64) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if contentLength > maxBytes {
+		return &LimitError{Limit: LimitMaxBytes, Max: maxBytes}
+	}
+	return nil
+}
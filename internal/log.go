@@ -0,0 +1,9 @@
+package internal
+
+import (
+	"log"
+	"os"
+)
+
+// Logger writes to stderr, so that it does not interfere with images written to stdout.
+var Logger = log.New(os.Stderr, "", log.LstdFlags)
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ReadMjpegFrame reads frames from a multipart/x-mixed-replace MJPEG stream until it reaches the given frame, counting from 1.
+// The caller should close the response body as soon as this returns, to disconnect from the stream.
+// A response that is a single image, such as a camera snapshot, is treated as a stream with one frame.
+func ReadMjpegFrame(res *http.Response, frame int, maxBytes int64) ([]byte, error) {
+	contentType := res.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("not an MJPEG stream: invalid content type \"%s\"", contentType)
+	}
+
+	if strings.HasPrefix(mediaType, "image/") {
+		if frame != 1 {
+			return nil, fmt.Errorf("the server sent a single image, not an MJPEG stream, so frame %d is not available", frame)
+		}
+		return io.ReadAll(LimitReader(res.Body, maxBytes))
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("not an MJPEG stream: content type is %s", mediaType)
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("not an MJPEG stream: content type has no boundary")
+	}
+	body := bufio.NewReader(res.Body)
+	boundary = fixBoundary(body, boundary)
+
+	parts := multipart.NewReader(body, boundary)
+	for count := 1; ; {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("the MJPEG stream ended after %d frames, before frame %d", count-1, frame)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the MJPEG stream: %w", err)
+		}
+
+		partType := part.Header.Get("Content-Type")
+		if partType != "" && !strings.HasPrefix(partType, "image/") {
+			continue
+		}
+		if count < frame {
+			count++
+			continue
+		}
+
+		data, err := io.ReadAll(LimitReader(part, maxBytes))
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+// fixBoundary handles cameras that include the leading "--" of the delimiter in the boundary parameter.
+func fixBoundary(body *bufio.Reader, boundary string) string {
+	if !strings.HasPrefix(boundary, "--") {
+		return boundary
+	}
+	start, _ := body.Peek(1024)
+	if bytes.Contains(start, []byte("--"+boundary)) {
+		return boundary
+	}
+	return strings.TrimPrefix(boundary, "--")
+}
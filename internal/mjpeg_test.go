@@ -0,0 +1,144 @@
+package internal_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ReadMjpegFrame", func() {
+	var (
+		server       *httptest.Server
+		contentType  string
+		delimiter    string
+		frames       int
+		disconnected chan bool
+	)
+
+	BeforeEach(func() {
+		contentType = "multipart/x-mixed-replace; boundary=frame"
+		delimiter = "--frame"
+		frames = -1
+		disconnected = make(chan bool, 1)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			flusher := w.(http.Flusher)
+			for i := 1; frames < 0 || i <= frames; i++ {
+				data := fmt.Sprintf("jpeg frame %d", i)
+				_, err := fmt.Fprintf(w, "%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n%s\r\n", delimiter, len(data), data)
+				if err != nil {
+					disconnected <- true
+					return
+				}
+				flusher.Flush()
+				select {
+				case <-r.Context().Done():
+					disconnected <- true
+					return
+				default:
+				}
+			}
+			_, _ = fmt.Fprintf(w, "%s--\r\n", delimiter)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	read := func(frame int) ([]byte, error) {
+		res, err := http.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		defer func() { _ = res.Body.Close() }()
+		return internal.ReadMjpegFrame(res, frame, 1024)
+	}
+
+	It("reads the first frame and disconnects", func() {
+		data, err := read(1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("jpeg frame 1"))
+		Eventually(disconnected).Should(Receive())
+	})
+
+	It("reads the requested frame", func() {
+		data, err := read(3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("jpeg frame 3"))
+	})
+
+	When("the boundary parameter includes the leading dashes", func() {
+		BeforeEach(func() {
+			contentType = "multipart/x-mixed-replace; boundary=--myboundary"
+			delimiter = "--myboundary"
+		})
+
+		It("reads the frame", func() {
+			data, err := read(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("jpeg frame 2"))
+		})
+	})
+
+	When("the stream ends before the requested frame", func() {
+		BeforeEach(func() {
+			frames = 2
+		})
+
+		It("returns an error", func() {
+			_, err := read(5)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("the MJPEG stream ended after 2 frames, before frame 5"))
+		})
+	})
+
+	When("the frame is too large", func() {
+		It("returns an error", func() {
+			res, err := http.Get(server.URL)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() { _ = res.Body.Close() }()
+			_, err = internal.ReadMjpegFrame(res, 1, 5)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the server sends a single image", func() {
+		BeforeEach(func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/jpeg")
+				_, _ = w.Write([]byte("jpeg snapshot"))
+			})
+		})
+
+		It("returns the image as the first frame", func() {
+			data, err := read(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("jpeg snapshot"))
+		})
+
+		It("returns an error for later frames", func() {
+			_, err := read(2)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("the server sent a single image, not an MJPEG stream, so frame 2 is not available"))
+		})
+	})
+
+	When("the server does not send a stream", func() {
+		BeforeEach(func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte("<html></html>"))
+			})
+		})
+
+		It("returns an error", func() {
+			_, err := read(1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("not an MJPEG stream: content type is text/html"))
+		})
+	})
+})
@@ -0,0 +1,351 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("pbm", "P1", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P2", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P3", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pbm", "P4", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pgm", "P5", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P6", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pam", "P7", decodeNetpbm, decodeNetpbmConfig)
+}
+
+// netpbmHeader describes the image in a PBM, PGM, PPM or PAM file.
+type netpbmHeader struct {
+	magic         string
+	width, height int
+	depth         int
+	maxValue      int
+	tupleType     string
+}
+
+func (h *netpbmHeader) colorModel() color.Model {
+	switch {
+	case h.depth == 2 || h.depth == 4:
+		if h.maxValue > 255 {
+			return color.NRGBA64Model
+		}
+		return color.NRGBAModel
+	case h.depth == 3 && h.maxValue > 255:
+		return color.RGBA64Model
+	case h.depth == 3:
+		return color.RGBAModel
+	case h.maxValue > 255:
+		return color.Gray16Model
+	}
+	return color.GrayModel
+}
+
+// netpbmReader reads the whitespace separated values in Netpbm headers and plain format image data, skipping comments.
+type netpbmReader struct {
+	*bufio.Reader
+}
+
+func (r *netpbmReader) skipSpace() error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case b == '#':
+			if _, err := r.ReadString('\n'); err != nil {
+				return err
+			}
+		case b != ' ' && b != '\t' && b != '\r' && b != '\n' && b != '\v' && b != '\f':
+			return r.UnreadByte()
+		}
+	}
+}
+
+func (r *netpbmReader) number() (int, error) {
+	if err := r.skipSpace(); err != nil {
+		return 0, err
+	}
+	n := 0
+	digits := 0
+	for {
+		b, err := r.ReadByte()
+		if errors.Is(err, io.EOF) && digits > 0 {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b < '0' || b > '9' {
+			if digits == 0 {
+				return 0, fmt.Errorf("netpbm: expected a number, found %q", b)
+			}
+			return n, r.UnreadByte()
+		}
+		n = n*10 + int(b-'0')
+		digits++
+		if n > 1<<24 {
+			return 0, fmt.Errorf("netpbm: number is too large")
+		}
+	}
+}
+
+// bit reads one value of a plain PBM file, where values do not need to be separated.
+func (r *netpbmReader) bit() (int, error) {
+	if err := r.skipSpace(); err != nil {
+		return 0, err
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '0' && b != '1' {
+		return 0, fmt.Errorf("netpbm: expected 0 or 1, found %q", b)
+	}
+	return int(b - '0'), nil
+}
+
+func readNetpbmHeader(r *netpbmReader) (*netpbmHeader, error) {
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	header := &netpbmHeader{magic: string(magic), depth: 1, maxValue: 1}
+	switch header.magic {
+	case "P1", "P2", "P3", "P4", "P5", "P6":
+		var err error
+		if header.width, err = r.number(); err != nil {
+			return nil, err
+		}
+		if header.height, err = r.number(); err != nil {
+			return nil, err
+		}
+		if header.magic != "P1" && header.magic != "P4" {
+			if header.maxValue, err = r.number(); err != nil {
+				return nil, err
+			}
+		}
+		if header.magic == "P3" || header.magic == "P6" {
+			header.depth = 3
+		}
+		// A single whitespace character separates the header from binary data
+		if _, err := r.ReadByte(); err != nil {
+			return nil, err
+		}
+	case "P7":
+		if err := readPamHeader(r, header); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("netpbm: unknown format %q", header.magic)
+	}
+
+	if header.width <= 0 || header.height <= 0 {
+		return nil, fmt.Errorf("netpbm: invalid size %dx%d", header.width, header.height)
+	}
+	if header.maxValue <= 0 || header.maxValue > 65535 {
+		return nil, fmt.Errorf("netpbm: invalid maximum value %d", header.maxValue)
+	}
+	return header, nil
+}
+
+func readPamHeader(r *netpbmReader, header *netpbmHeader) error {
+	header.depth = 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("netpbm: incomplete PAM header: %w", err)
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "ENDHDR" {
+			break
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("netpbm: invalid PAM header line %q", strings.TrimSpace(line))
+		}
+		if fields[0] == "TUPLTYPE" {
+			header.tupleType = strings.Join(fields[1:], " ")
+			continue
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("netpbm: invalid PAM header line %q", strings.TrimSpace(line))
+		}
+		switch fields[0] {
+		case "WIDTH":
+			header.width = value
+		case "HEIGHT":
+			header.height = value
+		case "DEPTH":
+			header.depth = value
+		case "MAXVAL":
+			header.maxValue = value
+		}
+	}
+	if header.depth < 1 || header.depth > 4 {
+		return &UnsupportedFormatError{Format: fmt.Sprintf("pam with depth %d", header.depth)}
+	}
+	return nil
+}
+
+func decodeNetpbmConfig(r io.Reader) (image.Config, error) {
+	header, err := readNetpbmHeader(&netpbmReader{bufio.NewReader(r)})
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: header.colorModel(), Width: header.width, Height: header.height}, nil
+}
+
+func decodeNetpbm(r io.Reader) (image.Image, error) {
+	reader := &netpbmReader{bufio.NewReader(r)}
+	header, err := readNetpbmHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// PBM files use 1 for black, unlike PAM black and white images, which use 1 for white
+	invert := header.magic == "P1" || header.magic == "P4"
+	samples := make([]int, header.depth)
+	bounds := image.Rect(0, 0, header.width, header.height)
+	var im interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	switch header.colorModel() {
+	case color.NRGBA64Model:
+		im = image.NewNRGBA64(bounds)
+	case color.NRGBAModel:
+		im = image.NewNRGBA(bounds)
+	case color.RGBA64Model:
+		im = image.NewRGBA64(bounds)
+	case color.RGBAModel:
+		im = image.NewRGBA(bounds)
+	case color.Gray16Model:
+		im = image.NewGray16(bounds)
+	default:
+		im = image.NewGray(bounds)
+	}
+
+	for y := 0; y < header.height; y++ {
+		var row []byte
+		if header.magic == "P4" {
+			row = make([]byte, (header.width+7)/8)
+			if _, err := io.ReadFull(reader, row); err != nil {
+				return nil, fmt.Errorf("netpbm: %w", err)
+			}
+		}
+		for x := 0; x < header.width; x++ {
+			for i := range samples {
+				switch header.magic {
+				case "P1":
+					samples[i], err = reader.bit()
+				case "P2", "P3":
+					samples[i], err = reader.number()
+				case "P4":
+					samples[i] = int(row[x/8]>>(7-x%8)) & 1
+				default:
+					samples[i], err = readNetpbmSample(reader, header.maxValue)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("netpbm: %w", err)
+				}
+				if samples[i] > header.maxValue {
+					return nil, fmt.Errorf("netpbm: value %d is larger than the maximum of %d", samples[i], header.maxValue)
+				}
+				if invert {
+					samples[i] = header.maxValue - samples[i]
+				}
+				samples[i] = samples[i] * 0xffff / header.maxValue
+			}
+			im.Set(x, y, netpbmColor(samples))
+		}
+	}
+	return im, nil
+}
+
+func readNetpbmSample(r io.ByteReader, maxValue int) (int, error) {
+	high, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if maxValue < 256 {
+		return int(high), nil
+	}
+	low, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return int(high)<<8 | int(low), nil
+}
+
+// netpbmColor makes a color from 16 bit samples: gray, gray and alpha, RGB, or RGB and alpha.
+func netpbmColor(samples []int) color.Color {
+	switch len(samples) {
+	case 2:
+		return color.NRGBA64{R: uint16(samples[0]), G: uint16(samples[0]), B: uint16(samples[0]), A: uint16(samples[1])}
+	case 3:
+		return color.RGBA64{R: uint16(samples[0]), G: uint16(samples[1]), B: uint16(samples[2]), A: 0xffff}
+	case 4:
+		return color.NRGBA64{R: uint16(samples[0]), G: uint16(samples[1]), B: uint16(samples[2]), A: uint16(samples[3])}
+	}
+	return color.Gray16{Y: uint16(samples[0])}
+}
+
+// encodeNetpbm writes the image as a binary PBM if it is only black and white, a PGM if it is grayscale, or a PPM otherwise.
+// Netpbm files have no transparency, so transparent areas are written as white.
+func encodeNetpbm(w io.Writer, im image.Image) error {
+	bounds := im.Bounds()
+	pixels := make([]color.RGBA, 0, bounds.Dx()*bounds.Dy())
+	blackAndWhite, gray := true, true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := im.At(x, y).RGBA()
+			// Draw over white
+			c := color.RGBA{R: uint8((r + 0xffff - a) >> 8), G: uint8((g + 0xffff - a) >> 8), B: uint8((b + 0xffff - a) >> 8), A: 0xff}
+			if c.R != c.G || c.G != c.B {
+				gray, blackAndWhite = false, false
+			} else if c.R != 0 && c.R != 0xff {
+				blackAndWhite = false
+			}
+			pixels = append(pixels, c)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	width := bounds.Dx()
+	switch {
+	case blackAndWhite:
+		_, _ = fmt.Fprintf(out, "P4\n%d %d\n", width, bounds.Dy())
+		row := make([]byte, (width+7)/8)
+		for y := 0; y < bounds.Dy(); y++ {
+			clear(row)
+			for x, c := range pixels[y*width : (y+1)*width] {
+				if c.R == 0 {
+					row[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+			_, _ = out.Write(row)
+		}
+	case gray:
+		_, _ = fmt.Fprintf(out, "P5\n%d %d\n255\n", width, bounds.Dy())
+		for _, c := range pixels {
+			_ = out.WriteByte(c.R)
+		}
+	default:
+		_, _ = fmt.Fprintf(out, "P6\n%d %d\n255\n", width, bounds.Dy())
+		for _, c := range pixels {
+			_, _ = out.Write([]byte{c.R, c.G, c.B})
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,129 @@
+package internal_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("Netpbm images", func() {
+	gray := func(im image.Image, x, y int) uint8 {
+		return color.GrayModel.Convert(im.At(x, y)).(color.Gray).Y
+	}
+
+	DescribeTable("decoding",
+		func(data string, expectedFormat string, check func(im image.Image)) {
+			Expect(internal.DetectImageFormat([]byte(data))).To(Equal(expectedFormat))
+			im, err := internal.DecodeImage(strings.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 3, 2)))
+			check(im)
+		},
+		Entry("plain PBM", "P1\n# a comment\n3 2\n1 0 1\n010\n", "pbm", func(im image.Image) {
+			Expect(gray(im, 0, 0)).To(Equal(uint8(0)))
+			Expect(gray(im, 1, 0)).To(Equal(uint8(255)))
+			Expect(gray(im, 1, 1)).To(Equal(uint8(0)))
+		}),
+		Entry("binary PBM", "P4\n3 2\n\xa0\x40", "pbm", func(im image.Image) {
+			Expect(gray(im, 0, 0)).To(Equal(uint8(0)))
+			Expect(gray(im, 1, 0)).To(Equal(uint8(255)))
+			Expect(gray(im, 1, 1)).To(Equal(uint8(0)))
+		}),
+		Entry("plain PGM", "P2 3 2 4\n0 2 4\n4 2 0\n", "pgm", func(im image.Image) {
+			Expect(gray(im, 0, 0)).To(Equal(uint8(0)))
+			Expect(gray(im, 1, 0)).To(Equal(uint8(127)))
+			Expect(gray(im, 2, 0)).To(Equal(uint8(255)))
+		}),
+		Entry("binary PGM", "P5\n3 2\n255\n\x00\x80\xff\xff\x80\x00", "pgm", func(im image.Image) {
+			Expect(gray(im, 1, 0)).To(Equal(uint8(128)))
+			Expect(gray(im, 0, 1)).To(Equal(uint8(255)))
+		}),
+		Entry("16 bit PGM", "P5\n3 2\n65535\n\x00\x00\x80\x00\xff\xff\xff\xff\x80\x00\x00\x00", "pgm", func(im image.Image) {
+			Expect(im.ColorModel()).To(Equal(color.Gray16Model))
+			Expect(gray(im, 2, 0)).To(Equal(uint8(255)))
+		}),
+		Entry("plain PPM", "P3\n3 2\n255\n255 0 0  0 255 0  0 0 255\n0 0 0  255 255 255  10 20 30\n", "ppm", func(im image.Image) {
+			Expect(color.RGBAModel.Convert(im.At(0, 0))).To(Equal(color.RGBA{R: 255, A: 255}))
+			Expect(color.RGBAModel.Convert(im.At(2, 1))).To(Equal(color.RGBA{R: 10, G: 20, B: 30, A: 255}))
+		}),
+		Entry("binary PPM", "P6\n3 2\n255\n\xff\x00\x00\x00\xff\x00\x00\x00\xff\x00\x00\x00\xff\xff\xff\x0a\x14\x1e", "ppm", func(im image.Image) {
+			Expect(color.RGBAModel.Convert(im.At(1, 0))).To(Equal(color.RGBA{G: 255, A: 255}))
+			Expect(color.RGBAModel.Convert(im.At(2, 1))).To(Equal(color.RGBA{R: 10, G: 20, B: 30, A: 255}))
+		}),
+		Entry("PAM", "P7\nWIDTH 3\nHEIGHT 2\nDEPTH 2\nMAXVAL 255\nTUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x00\xff\x80\xff\xff\x00\xff\xff\xff\xff\xff\xff", "pam", func(im image.Image) {
+			Expect(color.NRGBAModel.Convert(im.At(1, 0))).To(Equal(color.NRGBA{R: 128, G: 128, B: 128, A: 255}))
+			Expect(color.NRGBAModel.Convert(im.At(2, 0)).(color.NRGBA).A).To(Equal(uint8(0)))
+		}),
+	)
+
+	It("checks the pixel limit before decoding", func() {
+		_, err := internal.DecodeImage(strings.NewReader("P5\n1000 1000\n255\n"), &internal.DecodeOptions{MaxPixels: 1000})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("image exceeds maxPixels: 1000x1000 is 1000000 pixels, more than the limit of 1000"))
+	})
+
+	It("returns an error when the data is incomplete", func() {
+		_, err := internal.DecodeImage(strings.NewReader("P5\n3 2\n255\n\x00\x80"), nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("encoding", func() {
+		encode := func(im image.Image) string {
+			var buf bytes.Buffer
+			Expect(internal.EncodeImage(&buf, im, internal.FormatNetpbm)).To(Succeed())
+			return buf.String()
+		}
+
+		It("writes black and white images as PBM", func() {
+			im := image.NewRGBA(image.Rect(0, 0, 10, 2))
+			for x := range 10 {
+				im.Set(x, 0, color.White)
+				im.Set(x, 1, color.Black)
+			}
+			im.Set(0, 0, color.Black)
+			Expect(encode(im)).To(Equal("P4\n10 2\n\x80\x00\xff\xc0"))
+		})
+
+		It("writes grayscale images as PGM", func() {
+			im := image.NewGray(image.Rect(0, 0, 2, 1))
+			im.SetGray(0, 0, color.Gray{Y: 0x80})
+			im.SetGray(1, 0, color.Gray{Y: 0xff})
+			Expect(encode(im)).To(Equal("P5\n2 1\n255\n\x80\xff"))
+		})
+
+		It("writes color images as PPM", func() {
+			im := image.NewRGBA(image.Rect(0, 0, 2, 1))
+			im.Set(0, 0, color.RGBA{R: 255, A: 255})
+			im.Set(1, 0, color.RGBA{G: 10, B: 20, A: 255})
+			Expect(encode(im)).To(Equal("P6\n2 1\n255\n\xff\x00\x00\x00\x0a\x14"))
+		})
+
+		It("writes transparent areas as white", func() {
+			im := image.NewRGBA(image.Rect(0, 0, 2, 1))
+			im.Set(0, 0, color.Black)
+			Expect(encode(im)).To(Equal("P4\n2 1\n\x80"))
+		})
+
+		It("can be decoded again", func() {
+			im := image.NewGray(image.Rect(0, 0, 3, 3))
+			im.SetGray(1, 1, color.Gray{Y: 0x40})
+			decoded, err := internal.DecodeImage(strings.NewReader(encode(im)), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(im))
+		})
+	})
+
+	When("the output format is not known", func() {
+		It("returns an error", func() {
+			err := internal.EncodeImage(&bytes.Buffer{}, image.NewGray(image.Rect(0, 0, 1, 1)), "jpeg")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("unknown output format: jpeg"))
+		})
+	})
+})
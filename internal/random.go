@@ -0,0 +1,8 @@
+package internal
+
+import "math/rand/v2"
+
+// RandomInt returns a random number in [0, n).
+var RandomInt = func(n int) int {
+	return rand.IntN(n)
+}
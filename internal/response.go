@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const sniffLength = 512
+
+// xmlSniffLength is how far into an XML document to look for the root element, past a long prolog of comments or a DOCTYPE.
+const xmlSniffLength = 64 * 1024
+
+// sniff returns the start of the data, reading further into XML documents so that SVG images with a long prolog are recognized.
+// The reader must have a buffer of at least xmlSniffLength.
+func sniff(r *bufio.Reader) ([]byte, error) {
+	header, err := r.Peek(sniffLength)
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("<?")) || bytes.HasPrefix(trimmed, []byte("<!")) {
+		header, err = r.Peek(xmlSniffLength)
+	}
+	return header, err
+}
+
+type HttpStatusError struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+}
+
+func (e *HttpStatusError) Error() string {
+	if e.ContentType == "" {
+		return fmt.Sprintf("server responded with %s", e.Status)
+	}
+	return fmt.Sprintf("server responded with %s (content type: %s)", e.Status, e.ContentType)
+}
+
+type ContentTypeError struct {
+	ContentType string
+	Detected    string
+}
+
+func (e *ContentTypeError) Error() string {
+	if e.ContentType == "" {
+		return fmt.Sprintf("response is not an image (detected content type: %s)", e.Detected)
+	}
+	return fmt.Sprintf("response is not an image (content type: %s, detected content type: %s)", e.ContentType, e.Detected)
+}
+
+// genericContentTypes are sent by servers that do not know what they are serving, so the data is sniffed instead.
+var genericContentTypes = map[string]bool{
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+	"application/binary":       true,
+	"application/unknown":      true,
+}
+
+func statusError(res *http.Response) error {
+	return &HttpStatusError{
+		StatusCode:  res.StatusCode,
+		Status:      res.Status,
+		ContentType: res.Header.Get("Content-Type"),
+	}
+}
+
+// CheckStatus returns an error if the response was not successful.
+func CheckStatus(res *http.Response) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return statusError(res)
+	}
+	return nil
+}
+
+// ValidateResponse checks that the response was successful and that its body looks like an image.
+// The returned reader must be used in place of the response body.
+func ValidateResponse(res *http.Response) (io.Reader, error) {
+	if err := CheckStatus(res); err != nil {
+		return nil, err
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	body := bufio.NewReaderSize(res.Body, xmlSniffLength)
+	header, err := sniff(body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (!strings.HasPrefix(mediaType, "image/") && !genericContentTypes[mediaType]) {
+			return nil, &ContentTypeError{ContentType: contentType, Detected: http.DetectContentType(header)}
+		}
+	}
+
+	if DetectImageFormat(header) == "" {
+		return nil, &ContentTypeError{ContentType: contentType, Detected: http.DetectContentType(header)}
+	}
+	return body, nil
+}
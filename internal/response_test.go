@@ -0,0 +1,103 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ValidateResponse", func() {
+	var res *http.Response
+
+	BeforeEach(func() {
+		res = &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"image/gif"}},
+			Body:       io.NopCloser(bytes.NewReader([]byte("GIF89a image data"))),
+		}
+	})
+
+	It("returns the whole body", func() {
+		body, err := internal.ValidateResponse(res)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("GIF89a image data"))
+	})
+
+	It("accepts generic content types", func() {
+		res.Header.Set("Content-Type", "application/octet-stream")
+		_, err := internal.ValidateResponse(res)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("accepts SVG images with a long prolog", func() {
+		svg := "<?xml version=\"1.0\"?>\n<!-- " + strings.Repeat("Exported from a drawing program. ", 100) + "-->\n" +
+			`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`
+		res.Header.Set("Content-Type", "image/svg+xml")
+		res.Body = io.NopCloser(strings.NewReader(svg))
+		body, err := internal.ValidateResponse(res)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(svg))
+	})
+
+	It("accepts responses without a content type", func() {
+		res.Header.Del("Content-Type")
+		_, err := internal.ValidateResponse(res)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	When("the response was not successful", func() {
+		BeforeEach(func() {
+			res.StatusCode = http.StatusForbidden
+			res.Status = "403 Forbidden"
+			res.Header.Set("Content-Type", "text/plain")
+		})
+
+		It("returns a status error", func() {
+			_, err := internal.ValidateResponse(res)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("server responded with 403 Forbidden (content type: text/plain)"))
+			var statusErr *internal.HttpStatusError
+			Expect(errors.As(err, &statusErr)).To(BeTrue())
+			Expect(statusErr.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	When("the content type is not an image", func() {
+		BeforeEach(func() {
+			res.Header.Set("Content-Type", "text/html; charset=utf-8")
+		})
+
+		It("returns a content type error", func() {
+			_, err := internal.ValidateResponse(res)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("response is not an image (content type: text/html; charset=utf-8, detected content type: image/gif)"))
+		})
+	})
+
+	When("the data is not an image", func() {
+		BeforeEach(func() {
+			res.Header.Del("Content-Type")
+			res.Body = io.NopCloser(bytes.NewReader([]byte("<!DOCTYPE html><html></html>")))
+		})
+
+		It("returns a content type error", func() {
+			_, err := internal.ValidateResponse(res)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("response is not an image (detected content type: text/html; charset=utf-8)"))
+			var contentTypeErr *internal.ContentTypeError
+			Expect(errors.As(err, &contentTypeErr)).To(BeTrue())
+		})
+	})
+})
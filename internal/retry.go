@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultConnectTimeout  = 10 * time.Second
+	DefaultRetries         = 2
+	DefaultRetryBackoff    = 1 * time.Second
+	DefaultMaxRetryBackoff = 30 * time.Second
+)
+
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	if e.Attempts == 1 {
+		return fmt.Sprintf("request failed after 1 attempt: %s", e.Err)
+	}
+	return fmt.Sprintf("request failed after %d attempts: %s", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func isTransientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// backoff returns the delay before the given retry, growing exponentially with up to 50% random jitter.
+func backoff(retry int, initial, maximum time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < retry && delay < maximum; i++ {
+		delay *= 2
+	}
+	if delay > maximum {
+		delay = maximum
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func retryAfter(res *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// RedactURL returns a URL that is safe to log, without the password or query string, which often hold credentials or API keys.
+func RedactURL(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return DescribeSource(source)
+	}
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.Redacted()
+}
+
+func doWithRetries(client *http.Client, source string, options *HttpOptions, header http.Header) (*http.Response, error) {
+	attempts := options.Retries + 1
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		if delay > 0 {
+			Sleep(delay)
+		}
+		Logger.Printf("fetching %s (attempt %d of %d)", RedactURL(source), attempt, attempts)
+
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		options.apply(req)
+
+		res, err := client.Do(req)
+		if err == nil && !isRetryableStatus(res.StatusCode) {
+			return res, nil
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = RedactURL(urlErr.URL)
+		}
+
+		delay = backoff(attempt, options.RetryBackoff, options.MaxRetryBackoff)
+		if err == nil {
+			err = statusError(res)
+			if wait := retryAfter(res); wait > delay {
+				delay = min(wait, options.MaxRetryBackoff)
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+			_ = res.Body.Close()
+		} else if !isTransientError(err) {
+			return nil, &RetryError{Attempts: attempt, Err: err}
+		}
+
+		Logger.Printf("attempt %d of %d for %s failed: %s", attempt, attempts, RedactURL(source), err)
+		if attempt >= attempts {
+			return nil, &RetryError{Attempts: attempt, Err: err}
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SchemeS3 = "s3"
+
+	DefaultS3Region = "us-east-1"
+
+	// maxS3ListPages stops listing a prefix with a huge number of objects from taking forever.
+	maxS3ListPages = 100
+)
+
+// emptyPayloadHash is the SHA-256 hash of an empty request body.
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+// S3Options configures access to S3-compatible object storage.
+// Anything that is not set is read from the standard AWS environment variables and shared credentials file.
+// Requests are not signed if no credentials are found, which works for public buckets.
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	CredentialsFile string
+	Profile         string
+	PathStyle       bool
+}
+
+// S3Location is the bucket and key of an s3:// source.
+type S3Location struct {
+	Bucket string
+	Key    string
+}
+
+// IsPrefix returns true if the location refers to a prefix of many objects, rather than a single object.
+func (l *S3Location) IsPrefix() bool {
+	return l.Key == "" || strings.HasSuffix(l.Key, "/")
+}
+
+// S3Error is the error returned by the S3 API.
+type S3Error struct {
+	Status  string
+	Code    string
+	Message string
+}
+
+func (e *S3Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("S3 responded with %s: %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("S3 responded with %s: %s: %s", e.Status, e.Code, e.Message)
+}
+
+func ParseS3Source(source string) (*S3Location, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("S3 source is missing a bucket name: \"%s\"", source)
+	}
+	return &S3Location{Bucket: u.Host, Key: strings.TrimPrefix(u.Path, "/")}, nil
+}
+
+func (o *S3Options) region() string {
+	for _, region := range []string{o.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+	return DefaultS3Region
+}
+
+func (o *S3Options) endpoint() (*url.URL, error) {
+	endpoint := o.Endpoint
+	for _, value := range []string{os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")} {
+		if endpoint == "" {
+			endpoint = value
+		}
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", o.region())
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	if (u.Scheme != SchemeHttp && u.Scheme != SchemeHttps) || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: \"%s\", must be an http or https URL", endpoint)
+	}
+	return u, nil
+}
+
+// ValidateS3Endpoint returns an error if the endpoint is not an http or https URL.
+func ValidateS3Endpoint(endpoint string) error {
+	_, err := (&S3Options{Endpoint: endpoint}).endpoint()
+	return err
+}
+
+// credentials returns the access key, secret key and session token, from the options, the environment,
+// or the shared credentials file, in that order. All three are empty if no credentials were found.
+func (o *S3Options) credentials() (string, string, string, error) {
+	if o.AccessKeyID != "" {
+		return o.AccessKeyID, o.SecretAccessKey, o.SessionToken, nil
+	}
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return id, os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"), nil
+	}
+
+	file := o.CredentialsFile
+	if file == "" {
+		file = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	explicit := file != ""
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", "", nil
+		}
+		file = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := o.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	values, err := readCredentialsFile(file, profile)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return "", "", "", nil
+		}
+		return "", "", "", fmt.Errorf("failed to read S3 credentials file: %w", err)
+	}
+	if values == nil {
+		if explicit {
+			return "", "", "", fmt.Errorf("profile \"%s\" not found in S3 credentials file: %s", profile, file)
+		}
+		return "", "", "", nil
+	}
+	return values["aws_access_key_id"], values["aws_secret_access_key"], values["aws_session_token"], nil
+}
+
+// readCredentialsFile reads a profile from an AWS shared credentials file. It returns nil if the profile is not in the file.
+func readCredentialsFile(file, profile string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var values map[string]string
+	inProfile := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			if inProfile && values == nil {
+				values = map[string]string{}
+			}
+			continue
+		}
+		if !inProfile {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// s3Escape percent-encodes everything except the unreserved characters, as required for signing.
+func s3Escape(value string, keepSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || (keepSlash && b == '/') {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(key, false)+"="+s3Escape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// objectURL returns the URL of an object, or of the bucket if the key is empty.
+func (o *S3Options) objectURL(bucket, key string, query url.Values) (string, error) {
+	endpoint, err := o.endpoint()
+	if err != nil {
+		return "", err
+	}
+
+	u := *endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if o.PathStyle {
+		path += "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		path += "/" + key
+	}
+	u.Path = path
+	u.RawPath = s3Escape(path, true)
+	u.RawQuery = s3Query(query)
+	return u.String(), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signer returns a function that signs requests with AWS Signature Version 4, or nil if there are no credentials.
+func (o *S3Options) signer() (func(req *http.Request), error) {
+	accessKey, secretKey, sessionToken, err := o.credentials()
+	if err != nil {
+		return nil, err
+	}
+	if accessKey == "" {
+		return nil, nil
+	}
+	if secretKey == "" {
+		return nil, fmt.Errorf("S3 credentials are missing the secret access key")
+	}
+
+	region := o.region()
+	return func(req *http.Request) {
+		SignS3Request(req, region, accessKey, secretKey, sessionToken)
+	}, nil
+}
+
+// SignS3Request signs a request that has no body with AWS Signature Version 4, at the time returned by Now.
+func SignS3Request(req *http.Request, region, accessKey, secretKey, sessionToken string) {
+	now := Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	scope := day + "/" + region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": emptyPayloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+		signedHeaders = append(signedHeaders, "x-amz-security-token")
+		headerValues["x-amz-security-token"] = sessionToken
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + headerValues[name] + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		emptyPayloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// s3Request returns the URL of the bucket or object and a copy of the options that will sign the request.
+func s3Request(location *S3Location, key string, query url.Values, options *HttpOptions) (string, *HttpOptions, error) {
+	options = options.withDefaults()
+	s3 := options.S3
+	if s3 == nil {
+		s3 = &S3Options{}
+	}
+
+	requestURL, err := s3.objectURL(location.Bucket, key, query)
+	if err != nil {
+		return "", nil, err
+	}
+	options.sign, err = s3.signer()
+	if err != nil {
+		return "", nil, err
+	}
+	return requestURL, options, nil
+}
+
+func getS3(source string, options *HttpOptions) (*http.Response, error) {
+	location, err := ParseS3Source(source)
+	if err != nil {
+		return nil, err
+	}
+	if location.IsPrefix() {
+		return nil, fmt.Errorf("S3 source refers to a prefix, not an object: %s", source)
+	}
+
+	requestURL, options, err := s3Request(location, location.Key, nil, options)
+	if err != nil {
+		return nil, err
+	}
+	return getHttp(requestURL, options)
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+type s3ErrorResult struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// ListS3Objects returns the keys of the objects under the prefix of an s3:// source.
+func ListS3Objects(source string, options *HttpOptions) ([]string, error) {
+	location, err := ParseS3Source(source)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	query := url.Values{"list-type": {"2"}, "prefix": {location.Key}}
+	for page := 0; page < maxS3ListPages; page++ {
+		requestURL, listOptions, err := s3Request(location, "", query, options)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := listS3Page(requestURL, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+
+	Logger.Printf("warning: only the first %d objects under %s were listed", len(keys), source)
+	return keys, nil
+}
+
+func listS3Page(requestURL string, options *HttpOptions) (*s3ListResult, error) {
+	client, err := options.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := doWithRetries(client, requestURL, options, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, DefaultMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckStatus(res); err != nil {
+		var s3Err s3ErrorResult
+		if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+			return nil, &S3Error{Status: res.Status, Code: s3Err.Code, Message: s3Err.Message}
+		}
+		return nil, err
+	}
+
+	var result s3ListResult
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse the S3 object list: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,18 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultStateDirectory returns the directory used for state that should survive reboots, following the XDG base directory spec.
+func DefaultStateDirectory() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "eink-radiator-image")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return DefaultCacheDirectory()
+	}
+	return filepath.Join(home, ".local", "state", "eink-radiator-image")
+}
@@ -0,0 +1,9 @@
+package internal
+
+import (
+	"io"
+	"os"
+)
+
+// Stdin is where images are read from when the source is "-". It is replaced by the command's input stream.
+var Stdin io.Reader = os.Stdin
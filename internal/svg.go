@@ -0,0 +1,503 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+const (
+	SvgFitResize  = "resize"
+	SvgFitContain = "contain"
+	SvgFitCover   = "cover"
+)
+
+// UnsupportedSvgError is returned for SVG features that cannot be drawn, rather than drawing the image incorrectly.
+type UnsupportedSvgError struct {
+	Feature string
+}
+
+func (e *UnsupportedSvgError) Error() string {
+	return fmt.Sprintf("unsupported SVG feature: %s", e.Feature)
+}
+
+// svgSkippedElements are not drawn. They are either only used by reference, which is not supported, or do not affect a still image.
+var svgSkippedElements = map[string]bool{
+	"defs": true, "title": true, "desc": true, "metadata": true, "clipPath": true, "mask": true, "pattern": true,
+	"linearGradient": true, "radialGradient": true, "filter": true, "symbol": true, "marker": true,
+	"animate": true, "animateMotion": true, "animateTransform": true, "set": true, "script": true,
+}
+
+type svgNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []svgNode  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+type svgPaint struct {
+	none         bool
+	currentColor bool
+	color        color.NRGBA
+}
+
+// svgStyle holds the inherited properties used to draw shapes.
+type svgStyle struct {
+	fill, stroke   svgPaint
+	fillOpacity    float64
+	strokeOpacity  float64
+	evenOdd        bool
+	strokeWidth    float64
+	lineCap        string
+	lineJoin       string
+	miterLimit     float64
+	currentColor   color.NRGBA
+	hidden         bool
+	transform      matrix
+	nonScalingLine bool
+}
+
+var defaultSvgStyle = svgStyle{
+	fill:          svgPaint{color: color.NRGBA{A: 255}},
+	stroke:        svgPaint{none: true},
+	fillOpacity:   1,
+	strokeOpacity: 1,
+	strokeWidth:   1,
+	lineCap:       "butt",
+	lineJoin:      "miter",
+	miterLimit:    4,
+	currentColor:  color.NRGBA{A: 255},
+	transform:     identityMatrix,
+}
+
+// IsSvg returns true if the data starts with an SVG document.
+func IsSvg(header []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(header))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t.Name.Local == "svg"
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+// decodeSvg draws an SVG document at the size given in the options, fitting its viewBox to that size in the same way as the scale setting.
+// If no size is given, it is drawn at its own size.
+func decodeSvg(r io.Reader, options *DecodeOptions, maxPixels int64) (image.Image, error) {
+	var root svgNode
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
+	}
+	if root.XMLName.Local != "svg" {
+		return nil, fmt.Errorf("not an SVG document: root element is <%s>", root.XMLName.Local)
+	}
+
+	viewBox, err := svgViewBox(root)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := viewBox.width, viewBox.height
+	if options != nil && options.Width > 0 && options.Height > 0 {
+		xScale, yScale := float64(options.Width)/viewBox.width, float64(options.Height)/viewBox.height
+		switch options.Fit {
+		case SvgFitContain:
+			xScale = math.Min(xScale, yScale)
+			yScale = xScale
+		case SvgFitCover:
+			xScale = math.Max(xScale, yScale)
+			yScale = xScale
+		}
+		width, height = viewBox.width*xScale, viewBox.height*yScale
+	}
+
+	size := image.Pt(max(1, int(math.Round(width))), max(1, int(math.Round(height))))
+	if err := checkPixels(image.Config{Width: size.X, Height: size.Y}, maxPixels); err != nil {
+		return nil, err
+	}
+
+	style, err := applySvgStyle(defaultSvgStyle, svgAttributes(root))
+	if err != nil {
+		return nil, err
+	}
+	style.transform = matrix{float64(size.X) / viewBox.width, 0, 0, float64(size.Y) / viewBox.height, 0, 0}.
+		then(matrix{1, 0, 0, 1, -viewBox.minX, -viewBox.minY})
+
+	canvas := image.NewRGBA(image.Rectangle{Max: size})
+	if err := drawSvgChildren(canvas, root, style); err != nil {
+		return nil, err
+	}
+	return canvas, nil
+}
+
+type svgBox struct {
+	minX, minY, width, height float64
+}
+
+// svgViewBox returns the area of the drawing to show, from the viewBox, or from the width and height if there is no viewBox.
+func svgViewBox(root svgNode) (svgBox, error) {
+	attrs := svgAttributes(root)
+	width, widthErr := parseSvgLength(attrs["width"])
+	height, heightErr := parseSvgLength(attrs["height"])
+	hasWidth, hasHeight := widthErr == nil && width > 0, heightErr == nil && height > 0
+
+	if value, ok := attrs["viewBox"]; ok {
+		numbers, err := parseNumberList(value)
+		if err != nil || len(numbers) != 4 || numbers[2] <= 0 || numbers[3] <= 0 {
+			return svgBox{}, fmt.Errorf("invalid SVG viewBox \"%s\"", value)
+		}
+		return svgBox{numbers[0], numbers[1], numbers[2], numbers[3]}, nil
+	}
+	if hasWidth && hasHeight {
+		return svgBox{0, 0, width, height}, nil
+	}
+	return svgBox{}, fmt.Errorf("SVG has no viewBox, and no width and height, so it cannot be scaled")
+}
+
+// svgAttributes returns the attributes of an element, with style properties taking priority over presentation attributes.
+func svgAttributes(node svgNode) map[string]string {
+	attrs := map[string]string{}
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == "" || attr.Name.Space == svgNamespace {
+			attrs[attr.Name.Local] = strings.TrimSpace(attr.Value)
+		}
+	}
+	if style, ok := attrs["style"]; ok {
+		for _, declaration := range strings.Split(style, ";") {
+			name, value, ok := strings.Cut(declaration, ":")
+			if ok {
+				value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+				attrs[strings.TrimSpace(name)] = value
+			}
+		}
+	}
+	return attrs
+}
+
+// parseSvgLength parses a length in user units, converting absolute units at 96 pixels per inch.
+func parseSvgLength(value string) (float64, error) {
+	units := map[string]float64{"": 1, "px": 1, "pt": 96.0 / 72, "pc": 16, "in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4, "em": 16, "ex": 8}
+	value = strings.TrimSpace(value)
+	number := strings.TrimRightFunc(value, func(r rune) bool { return r >= 'a' && r <= 'z' || r == '%' })
+	unit := value[len(number):]
+	scale, ok := units[unit]
+	if !ok {
+		return 0, &UnsupportedSvgError{Feature: fmt.Sprintf("length \"%s\"", value)}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid length \"%s\"", value)
+	}
+	return n * scale, nil
+}
+
+// applySvgStyle returns the style for an element, from its parent's style and its own attributes.
+func applySvgStyle(parent svgStyle, attrs map[string]string) (svgStyle, error) {
+	style := parent
+	for _, property := range []string{"clip-path", "mask", "filter", "marker-start", "marker-mid", "marker-end", "marker"} {
+		if value, ok := attrs[property]; ok && value != "none" && value != "" {
+			return style, &UnsupportedSvgError{Feature: property}
+		}
+	}
+	if value, ok := attrs["stroke-dasharray"]; ok && value != "none" && value != "" {
+		return style, &UnsupportedSvgError{Feature: "stroke-dasharray"}
+	}
+
+	var err error
+	set := func(name string, apply func(value string) error) {
+		if value, ok := attrs[name]; ok && value != "inherit" && err == nil {
+			if applyErr := apply(value); applyErr != nil {
+				err = fmt.Errorf("invalid %s: %w", name, applyErr)
+				var unsupported *UnsupportedSvgError
+				if errors.As(applyErr, &unsupported) {
+					err = applyErr
+				}
+			}
+		}
+	}
+	number := func(target *float64) func(string) error {
+		return func(value string) error {
+			n, err := parseSvgLength(value)
+			*target = n
+			return err
+		}
+	}
+	opacity := func(target *float64) func(string) error {
+		return func(value string) error {
+			percent := strings.HasSuffix(value, "%")
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if percent {
+				n /= 100
+			}
+			*target = math.Max(0, math.Min(1, n))
+			return err
+		}
+	}
+	paint := func(target *svgPaint) func(string) error {
+		return func(value string) error {
+			switch {
+			case value == "none":
+				*target = svgPaint{none: true}
+			case value == "currentColor":
+				*target = svgPaint{currentColor: true}
+			case strings.HasPrefix(value, "url("):
+				return &UnsupportedSvgError{Feature: fmt.Sprintf("gradient or pattern paint \"%s\"", value)}
+			default:
+				c, err := parseSvgColor(value)
+				if err != nil {
+					return err
+				}
+				*target = svgPaint{color: c}
+			}
+			return nil
+		}
+	}
+
+	set("color", func(value string) error {
+		c, err := parseSvgColor(value)
+		style.currentColor = c
+		return err
+	})
+	set("fill", paint(&style.fill))
+	set("stroke", paint(&style.stroke))
+	set("fill-opacity", opacity(&style.fillOpacity))
+	set("stroke-opacity", opacity(&style.strokeOpacity))
+	set("fill-rule", func(value string) error {
+		style.evenOdd = value == "evenodd"
+		return nil
+	})
+	set("stroke-width", number(&style.strokeWidth))
+	set("stroke-linecap", func(value string) error {
+		style.lineCap = value
+		return nil
+	})
+	set("stroke-linejoin", func(value string) error {
+		style.lineJoin = value
+		return nil
+	})
+	set("stroke-miterlimit", number(&style.miterLimit))
+	set("visibility", func(value string) error {
+		style.hidden = value == "hidden" || value == "collapse"
+		return nil
+	})
+	set("vector-effect", func(value string) error {
+		style.nonScalingLine = value == "non-scaling-stroke"
+		return nil
+	})
+	set("transform", func(value string) error {
+		m, err := parseTransform(value)
+		style.transform = parent.transform.then(m)
+		return err
+	})
+	return style, err
+}
+
+func drawSvgChildren(canvas *image.RGBA, node svgNode, style svgStyle) error {
+	for _, child := range node.Children {
+		if err := drawSvgNode(canvas, child, style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func drawSvgNode(canvas *image.RGBA, node svgNode, parent svgStyle) error {
+	name := node.XMLName.Local
+	if node.XMLName.Space != "" && node.XMLName.Space != svgNamespace {
+		// Elements from other namespaces, such as an editor's metadata, are ignored
+		return nil
+	}
+	if svgSkippedElements[name] {
+		return nil
+	}
+
+	attrs := svgAttributes(node)
+	if attrs["display"] == "none" {
+		return nil
+	}
+	style, err := applySvgStyle(parent, attrs)
+	if err != nil {
+		return err
+	}
+
+	// Elements with opacity are drawn on their own layer, which is then blended with what is below
+	if value, ok := attrs["opacity"]; ok {
+		opacity, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid opacity \"%s\"", value)
+		}
+		if opacity < 1 {
+			layer := image.NewRGBA(canvas.Bounds())
+			if err := drawSvgElement(layer, node, attrs, style); err != nil {
+				return err
+			}
+			alpha := uint8(math.Round(math.Max(0, opacity) * 255))
+			draw.DrawMask(canvas, canvas.Bounds(), layer, image.Point{}, image.NewUniform(color.Alpha{A: alpha}), image.Point{}, draw.Over)
+			return nil
+		}
+	}
+	return drawSvgElement(canvas, node, attrs, style)
+}
+
+func drawSvgElement(canvas *image.RGBA, node svgNode, attrs map[string]string, style svgStyle) error {
+	var path pathData
+	var err error
+	name := node.XMLName.Local
+	switch name {
+	case "g", "a":
+		return drawSvgChildren(canvas, node, style)
+	case "style":
+		if strings.TrimSpace(node.Text) == "" {
+			return nil
+		}
+		return &UnsupportedSvgError{Feature: "<style> stylesheets"}
+	case "path":
+		path, err = parsePathData(attrs["d"])
+		if err != nil {
+			return err
+		}
+	case "rect":
+		values, err := svgLengths(attrs, "x", "y", "width", "height", "rx", "ry")
+		if err != nil {
+			return err
+		}
+		x, y, w, h, rx, ry := values[0], values[1], values[2], values[3], values[4], values[5]
+		if _, ok := attrs["rx"]; !ok {
+			rx = ry
+		}
+		if _, ok := attrs["ry"]; !ok {
+			ry = rx
+		}
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		path.roundedRect(x, y, w, h, math.Min(rx, w/2), math.Min(ry, h/2))
+	case "circle":
+		values, err := svgLengths(attrs, "cx", "cy", "r")
+		if err != nil {
+			return err
+		}
+		if values[2] <= 0 {
+			return nil
+		}
+		path.ellipse(values[0], values[1], values[2], values[2])
+	case "ellipse":
+		values, err := svgLengths(attrs, "cx", "cy", "rx", "ry")
+		if err != nil {
+			return err
+		}
+		if values[2] <= 0 || values[3] <= 0 {
+			return nil
+		}
+		path.ellipse(values[0], values[1], values[2], values[3])
+	case "line":
+		values, err := svgLengths(attrs, "x1", "y1", "x2", "y2")
+		if err != nil {
+			return err
+		}
+		path.moveTo(point{values[0], values[1]})
+		path.lineTo(point{values[2], values[3]})
+	case "polyline", "polygon":
+		numbers, err := parseNumberList(attrs["points"])
+		if err != nil {
+			return fmt.Errorf("invalid points \"%s\"", attrs["points"])
+		}
+		for i := 0; i+1 < len(numbers); i += 2 {
+			if i == 0 {
+				path.moveTo(point{numbers[i], numbers[i+1]})
+			} else {
+				path.lineTo(point{numbers[i], numbers[i+1]})
+			}
+		}
+		if name == "polygon" && len(path) > 0 {
+			path.close()
+		}
+	case "svg":
+		return &UnsupportedSvgError{Feature: "nested <svg> elements"}
+	default:
+		return &UnsupportedSvgError{Feature: fmt.Sprintf("<%s> elements", name)}
+	}
+
+	if style.hidden {
+		return nil
+	}
+	return drawSvgPath(canvas, path, style)
+}
+
+func svgLengths(attrs map[string]string, names ...string) ([]float64, error) {
+	values := make([]float64, len(names))
+	for i, name := range names {
+		value, ok := attrs[name]
+		if !ok || value == "" {
+			continue
+		}
+		n, err := parseSvgLength(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		values[i] = n
+	}
+	return values, nil
+}
+
+func drawSvgPath(canvas *image.RGBA, path pathData, style svgStyle) error {
+	lines := path.flatten(style.transform)
+	size := canvas.Bounds().Size()
+
+	paintColor := func(paint svgPaint, opacity float64) *image.Uniform {
+		c := paint.color
+		if paint.currentColor {
+			c = style.currentColor
+		}
+		c.A = uint8(math.Round(float64(c.A) * opacity))
+		return image.NewUniform(c)
+	}
+
+	if !style.fill.none {
+		polygons := make([][]point, 0, len(lines))
+		for _, line := range lines {
+			if len(line.points) > 2 {
+				polygons = append(polygons, line.points)
+			}
+		}
+		if style.evenOdd && slices.ContainsFunc(polygons, crossesItself) {
+			return &UnsupportedSvgError{Feature: "fill-rule evenodd on a path that crosses itself"}
+		}
+		mask := rasterize(polygons, size.X, size.Y, style.evenOdd)
+		draw.DrawMask(canvas, mask.Rect, paintColor(style.fill, style.fillOpacity), image.Point{}, mask, mask.Rect.Min, draw.Over)
+	}
+
+	if !style.stroke.none && style.strokeWidth > 0 {
+		width := style.strokeWidth
+		if !style.nonScalingLine {
+			width *= style.transform.scale()
+		}
+		polygons := strokeOutline(lines, width, style.lineCap, style.lineJoin, style.miterLimit)
+		mask := rasterize(polygons, size.X, size.Y, false)
+		draw.DrawMask(canvas, mask.Rect, paintColor(style.stroke, style.strokeOpacity), image.Point{}, mask, mask.Rect.Min, draw.Over)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package internal_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("DecodeImage with an SVG image", func() {
+	var options *internal.DecodeOptions
+
+	BeforeEach(func() {
+		options = &internal.DecodeOptions{}
+	})
+
+	decode := func(svg string) image.Image {
+		im, err := internal.DecodeImage(strings.NewReader(svg), options)
+		Expect(err).ToNot(HaveOccurred())
+		return im
+	}
+
+	colorAt := func(im image.Image, x, y int) color.RGBA {
+		return color.RGBAModel.Convert(im.At(x, y)).(color.RGBA)
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	black := color.RGBA{A: 255}
+	transparent := color.RGBA{}
+
+	// A 200x100 drawing with a red left half
+	const halves = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100">
+  <rect width="100" height="100" fill="red"/>
+</svg>`
+
+	It("draws the image at its own size when no size is given", func() {
+		im := decode(halves)
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 200, 100)))
+		Expect(colorAt(im, 50, 50)).To(Equal(red))
+		Expect(colorAt(im, 150, 50)).To(Equal(transparent))
+	})
+
+	It("recognizes SVG images with a long prolog", func() {
+		prolog := "<?xml version=\"1.0\"?>\n<!-- " + strings.Repeat("Exported from a drawing program. ", 100) + "-->\n" +
+			"<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd\">\n"
+		im := decode(prolog + strings.TrimPrefix(halves, `<?xml version="1.0" encoding="UTF-8"?>`))
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 200, 100)))
+		Expect(colorAt(im, 50, 50)).To(Equal(red))
+	})
+
+	It("stretches the viewBox to the size when resizing", func() {
+		options.Width, options.Height, options.Fit = 400, 400, internal.SvgFitResize
+		im := decode(halves)
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 400, 400)))
+		Expect(colorAt(im, 199, 399)).To(Equal(red))
+		Expect(colorAt(im, 201, 0)).To(Equal(transparent))
+	})
+
+	It("fits the viewBox inside the size when containing", func() {
+		options.Width, options.Height, options.Fit = 400, 400, internal.SvgFitContain
+		im := decode(halves)
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 400, 200)))
+		Expect(colorAt(im, 199, 199)).To(Equal(red))
+	})
+
+	It("fills the size with the viewBox when covering", func() {
+		options.Width, options.Height, options.Fit = 400, 400, internal.SvgFitCover
+		im := decode(halves)
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 800, 400)))
+		Expect(colorAt(im, 399, 399)).To(Equal(red))
+		Expect(colorAt(im, 401, 0)).To(Equal(transparent))
+	})
+
+	It("uses the width and height when there is no viewBox", func() {
+		im := decode(`<svg xmlns="http://www.w3.org/2000/svg" width="1in" height="48pt"><rect x="10" y="10" width="10" height="10"/></svg>`)
+		Expect(im.Bounds()).To(Equal(image.Rect(0, 0, 96, 64)))
+		Expect(colorAt(im, 15, 15)).To(Equal(black))
+	})
+
+	It("draws paths, transforms, strokes and inherited styles", func() {
+		im := decode(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" viewBox="0 0 100 100">
+  <inkscape:page width="100" height="100"/>
+  <title>Test drawing</title>
+  <g fill="#00f" transform="translate(50 0)">
+    <path d="M0,0 h20 v20 h-20 z"/>
+    <circle cx="40" cy="10" r="5" style="fill: rgb(255, 0, 0)"/>
+  </g>
+  <path d="M10 50 L90 50" stroke="black" stroke-width="4"/>
+  <path fill-rule="evenodd" d="M10 70h20v20h-20z M15 75h10v10h-10z"/>
+  <polygon points="60,70 80,70 80,90" display="none"/>
+</svg>`)
+		Expect(colorAt(im, 60, 10)).To(Equal(color.RGBA{B: 255, A: 255}))
+		Expect(colorAt(im, 90, 10)).To(Equal(red))
+		Expect(colorAt(im, 50, 49)).To(Equal(black))
+		Expect(colorAt(im, 50, 53)).To(Equal(transparent))
+		Expect(colorAt(im, 12, 72)).To(Equal(black))
+		Expect(colorAt(im, 20, 80)).To(Equal(transparent))
+		Expect(colorAt(im, 78, 88)).To(Equal(transparent))
+	})
+
+	It("draws arcs and curves", func() {
+		im := decode(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+  <path d="M10 50 A40 40 0 0 1 90 50 Z"/>
+  <path d="M10 60 Q50 100 90 60 T10 60" fill="red"/>
+</svg>`)
+		Expect(colorAt(im, 50, 15)).To(Equal(black))
+		Expect(colorAt(im, 12, 15)).To(Equal(transparent))
+		Expect(colorAt(im, 50, 75)).To(Equal(red))
+	})
+
+	It("fills the middle of a star that crosses itself using the non-zero rule", func() {
+		im := decode(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><path d="M50 5 L79 95 L2 40 L98 40 L21 95 Z"/></svg>`)
+		Expect(colorAt(im, 50, 55)).To(Equal(black))
+		Expect(colorAt(im, 50, 20)).To(Equal(black))
+		Expect(colorAt(im, 50, 95)).To(Equal(transparent))
+	})
+
+	It("blends elements with opacity", func() {
+		im := decode(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10" fill="red" opacity="0.5"/></svg>`)
+		Expect(colorAt(im, 5, 5).A).To(BeNumerically("~", 128, 1))
+	})
+
+	When("the drawing uses an unsupported feature", func() {
+		It("returns an error that names the feature", func() {
+			for svg, feature := range map[string]string{
+				`<svg viewBox="0 0 10 10"><text x="0" y="10">Hello</text></svg>`:                                      "<text> elements",
+				`<svg viewBox="0 0 10 10"><rect width="10" height="10" fill="url(#gradient)"/></svg>`:                 "gradient or pattern paint \"url(#gradient)\"",
+				`<svg viewBox="0 0 10 10"><style>rect { fill: red }</style></svg>`:                                    "<style> stylesheets",
+				`<svg viewBox="0 0 10 10"><g clip-path="url(#clip)"/></svg>`:                                          "clip-path",
+				`<svg viewBox="0 0 10 10"><line x2="10" y2="10" stroke="red" stroke-dasharray="2"/></svg>`:            "stroke-dasharray",
+				`<svg viewBox="0 0 100 100"><path fill-rule="evenodd" d="M50 5 L79 95 L2 40 L98 40 L21 95 Z"/></svg>`: "fill-rule evenodd on a path that crosses itself",
+			} {
+				_, err := internal.DecodeImage(strings.NewReader(svg), options)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("unsupported SVG feature: " + feature))
+
+				var svgErr *internal.UnsupportedSvgError
+				Expect(errors.As(err, &svgErr)).To(BeTrue())
+			}
+		})
+	})
+
+	When("the drawing has no size", func() {
+		It("returns an error", func() {
+			_, err := internal.DecodeImage(strings.NewReader(`<svg><rect width="10" height="10"/></svg>`), options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("SVG has no viewBox, and no width and height, so it cannot be scaled"))
+		})
+	})
+
+	When("the drawn image would have more pixels than the limit", func() {
+		It("returns a limit error", func() {
+			options.Width, options.Height, options.Fit, options.MaxPixels = 400, 400, internal.SvgFitCover, 100000
+			_, err := internal.DecodeImage(strings.NewReader(halves), options)
+			var limitErr *internal.LimitError
+			Expect(errors.As(err, &limitErr)).To(BeTrue())
+			Expect(limitErr.Width).To(Equal(800))
+		})
+	})
+})
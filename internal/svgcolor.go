@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// parseSvgColor parses a CSS color: a keyword, #rgb, #rgba, #rrggbb, #rrggbbaa, rgb() or rgba().
+func parseSvgColor(value string) (color.NRGBA, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "transparent" {
+		return color.NRGBA{}, nil
+	}
+	if named, ok := colornames.Map[value]; ok {
+		return color.NRGBA{R: named.R, G: named.G, B: named.B, A: named.A}, nil
+	}
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok {
+		if len(hex) == 3 || len(hex) == 4 {
+			expanded := make([]byte, 0, 8)
+			for i := range len(hex) {
+				expanded = append(expanded, hex[i], hex[i])
+			}
+			hex = string(expanded)
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 8 {
+			return color.NRGBA{}, fmt.Errorf("invalid color \"%s\"", value)
+		}
+		return color.NRGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+	}
+
+	name, args, ok := strings.Cut(value, "(")
+	if ok && (name == "rgb" || name == "rgba") && strings.HasSuffix(args, ")") {
+		parts := strings.FieldsFunc(strings.TrimSuffix(args, ")"), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(parts) == 3 || len(parts) == 4 {
+			c := color.NRGBA{A: 255}
+			channels := []*uint8{&c.R, &c.G, &c.B, &c.A}
+			for i, part := range parts {
+				scale := 1.0
+				if i == 3 {
+					scale = 255
+				}
+				if percent, ok := strings.CutSuffix(part, "%"); ok {
+					part, scale = percent, 2.55
+				}
+				n, err := strconv.ParseFloat(part, 64)
+				if err != nil {
+					return color.NRGBA{}, fmt.Errorf("invalid color \"%s\"", value)
+				}
+				*channels[i] = uint8(math.Round(math.Max(0, math.Min(255, n*scale))))
+			}
+			return c, nil
+		}
+	}
+
+	if ok {
+		return color.NRGBA{}, &UnsupportedSvgError{Feature: fmt.Sprintf("color \"%s\"", value)}
+	}
+	return color.NRGBA{}, fmt.Errorf("invalid color \"%s\"", value)
+}
@@ -0,0 +1,584 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type point struct {
+	x, y float64
+}
+
+func (p point) add(q point) point     { return point{p.x + q.x, p.y + q.y} }
+func (p point) sub(q point) point     { return point{p.x - q.x, p.y - q.y} }
+func (p point) mul(s float64) point   { return point{p.x * s, p.y * s} }
+func (p point) length() float64       { return math.Hypot(p.x, p.y) }
+func (p point) cross(q point) float64 { return p.x*q.y - p.y*q.x }
+func (p point) dot(q point) float64   { return p.x*q.x + p.y*q.y }
+func (p point) normal() point         { return point{-p.y, p.x} }
+func (p point) lerp(q point, t float64) point {
+	return point{p.x + (q.x-p.x)*t, p.y + (q.y-p.y)*t}
+}
+
+// matrix is an affine transform [a b c d e f], mapping (x, y) to (ax + cy + e, bx + dy + f), as in the SVG transform attribute.
+type matrix [6]float64
+
+var identityMatrix = matrix{1, 0, 0, 1, 0, 0}
+
+// then returns the transform that applies n, then m.
+func (m matrix) then(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// scale returns how much the transform scales lengths, on average.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// parseTransform parses the list of transform functions in an SVG transform attribute.
+func parseTransform(value string) (matrix, error) {
+	result := identityMatrix
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		name, after, ok := strings.Cut(rest, "(")
+		args, remaining, closed := strings.Cut(after, ")")
+		if !ok || !closed {
+			return result, fmt.Errorf("invalid transform \"%s\"", value)
+		}
+		numbers, err := parseNumberList(args)
+		if err != nil {
+			return result, fmt.Errorf("invalid transform \"%s\"", value)
+		}
+
+		var m matrix
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "matrix" && len(numbers) == 6:
+			m = matrix(numbers)
+		case name == "translate" && len(numbers) == 1:
+			m = matrix{1, 0, 0, 1, numbers[0], 0}
+		case name == "translate" && len(numbers) == 2:
+			m = matrix{1, 0, 0, 1, numbers[0], numbers[1]}
+		case name == "scale" && len(numbers) == 1:
+			m = matrix{numbers[0], 0, 0, numbers[0], 0, 0}
+		case name == "scale" && len(numbers) == 2:
+			m = matrix{numbers[0], 0, 0, numbers[1], 0, 0}
+		case name == "rotate" && (len(numbers) == 1 || len(numbers) == 3):
+			sin, cos := math.Sincos(numbers[0] * math.Pi / 180)
+			m = matrix{cos, sin, -sin, cos, 0, 0}
+			if len(numbers) == 3 {
+				cx, cy := numbers[1], numbers[2]
+				m = matrix{1, 0, 0, 1, cx, cy}.then(m).then(matrix{1, 0, 0, 1, -cx, -cy})
+			}
+		case name == "skewX" && len(numbers) == 1:
+			m = matrix{1, 0, math.Tan(numbers[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(numbers) == 1:
+			m = matrix{1, math.Tan(numbers[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return result, fmt.Errorf("invalid transform \"%s\"", value)
+		}
+		result = result.then(m)
+		rest = strings.TrimLeft(remaining, " \t\r\n,")
+	}
+	return result, nil
+}
+
+func parseNumberList(value string) ([]float64, error) {
+	scanner := &pathScanner{data: value}
+	numbers := []float64{}
+	for scanner.skipSeparators(); !scanner.done(); scanner.skipSeparators() {
+		n, err := scanner.number()
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// segment is part of a path: a move, a line, a cubic Bézier curve or a close. Quadratic curves and arcs are converted to cubic curves.
+type segment struct {
+	command byte
+	points  [3]point
+}
+
+type pathData []segment
+
+func (p *pathData) moveTo(to point) {
+	*p = append(*p, segment{command: 'M', points: [3]point{to}})
+}
+
+func (p *pathData) lineTo(to point) {
+	*p = append(*p, segment{command: 'L', points: [3]point{to}})
+}
+
+func (p *pathData) cubicTo(c1, c2, to point) {
+	*p = append(*p, segment{command: 'C', points: [3]point{c1, c2, to}})
+}
+
+func (p *pathData) close() {
+	*p = append(*p, segment{command: 'Z'})
+}
+
+// kappa is the distance of the control points from the ends of a cubic curve that approximates a quarter circle.
+const kappa = 0.5522847498
+
+func (p *pathData) ellipse(cx, cy, rx, ry float64) {
+	kx, ky := rx*kappa, ry*kappa
+	p.moveTo(point{cx + rx, cy})
+	p.cubicTo(point{cx + rx, cy + ky}, point{cx + kx, cy + ry}, point{cx, cy + ry})
+	p.cubicTo(point{cx - kx, cy + ry}, point{cx - rx, cy + ky}, point{cx - rx, cy})
+	p.cubicTo(point{cx - rx, cy - ky}, point{cx - kx, cy - ry}, point{cx, cy - ry})
+	p.cubicTo(point{cx + kx, cy - ry}, point{cx + rx, cy - ky}, point{cx + rx, cy})
+	p.close()
+}
+
+func (p *pathData) roundedRect(x, y, w, h, rx, ry float64) {
+	if rx <= 0 || ry <= 0 {
+		p.moveTo(point{x, y})
+		p.lineTo(point{x + w, y})
+		p.lineTo(point{x + w, y + h})
+		p.lineTo(point{x, y + h})
+		p.close()
+		return
+	}
+	kx, ky := rx*kappa, ry*kappa
+	p.moveTo(point{x + rx, y})
+	p.lineTo(point{x + w - rx, y})
+	p.cubicTo(point{x + w - rx + kx, y}, point{x + w, y + ry - ky}, point{x + w, y + ry})
+	p.lineTo(point{x + w, y + h - ry})
+	p.cubicTo(point{x + w, y + h - ry + ky}, point{x + w - rx + kx, y + h}, point{x + w - rx, y + h})
+	p.lineTo(point{x + rx, y + h})
+	p.cubicTo(point{x + rx - kx, y + h}, point{x, y + h - ry + ky}, point{x, y + h - ry})
+	p.lineTo(point{x, y + ry})
+	p.cubicTo(point{x, y + ry - ky}, point{x + rx - kx, y}, point{x + rx, y})
+	p.close()
+}
+
+// arcTo adds an elliptical arc, converted from SVG's endpoint parameterization to cubic curves of at most 90 degrees each.
+func (p *pathData) arcTo(from point, rx, ry, rotation float64, large, sweep bool, to point) {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || from == to {
+		p.lineTo(to)
+		return
+	}
+
+	sinPhi, cosPhi := math.Sincos(rotation * math.Pi / 180)
+	dx, dy := (from.x-to.x)/2, (from.y-to.y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if large == sweep {
+		coefficient = -coefficient
+	}
+	cx1 := coefficient * rx * y1 / ry
+	cy1 := -coefficient * ry * x1 / rx
+	cx := cosPhi*cx1 - sinPhi*cy1 + (from.x+to.x)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (from.y+to.y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	start := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	pointAt := func(theta float64) point {
+		sin, cos := math.Sincos(theta)
+		return point{cx + rx*cos*cosPhi - ry*sin*sinPhi, cy + rx*cos*sinPhi + ry*sin*cosPhi}
+	}
+	derivativeAt := func(theta float64) point {
+		sin, cos := math.Sincos(theta)
+		return point{-rx*sin*cosPhi - ry*cos*sinPhi, -rx*sin*sinPhi + ry*cos*cosPhi}
+	}
+
+	count := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	if count == 0 {
+		p.lineTo(to)
+		return
+	}
+	step := delta / float64(count)
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	for i := range count {
+		theta1 := start + float64(i)*step
+		theta2 := theta1 + step
+		p1, p2 := pointAt(theta1), pointAt(theta2)
+		end := p2
+		if i == count-1 {
+			end = to
+		}
+		p.cubicTo(p1.add(derivativeAt(theta1).mul(k)), p2.sub(derivativeAt(theta2).mul(k)), end)
+	}
+}
+
+// pathScanner reads the commands, numbers and flags of SVG path data.
+type pathScanner struct {
+	data string
+	pos  int
+}
+
+func (s *pathScanner) done() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *pathScanner) skipSeparators() {
+	for !s.done() && strings.IndexByte(" \t\r\n,", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+func (s *pathScanner) hasNumber() bool {
+	s.skipSeparators()
+	return !s.done() && strings.IndexByte("0123456789+-.", s.data[s.pos]) >= 0
+}
+
+func (s *pathScanner) number() (float64, error) {
+	s.skipSeparators()
+	start := s.pos
+	if !s.done() && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+		s.pos++
+	}
+	digits := func() {
+		for !s.done() && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+			s.pos++
+		}
+	}
+	digits()
+	if !s.done() && s.data[s.pos] == '.' {
+		s.pos++
+		digits()
+	}
+	if !s.done() && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if !s.done() && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		digits()
+	}
+	n, err := strconv.ParseFloat(s.data[start:s.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number at position %d", start)
+	}
+	return n, nil
+}
+
+func (s *pathScanner) numbers(values ...*float64) error {
+	for _, value := range values {
+		n, err := s.number()
+		if err != nil {
+			return err
+		}
+		*value = n
+	}
+	return nil
+}
+
+// flag reads an arc flag, which may not be separated from the next value.
+func (s *pathScanner) flag() (bool, error) {
+	s.skipSeparators()
+	if s.done() || (s.data[s.pos] != '0' && s.data[s.pos] != '1') {
+		return false, fmt.Errorf("invalid arc flag at position %d", s.pos)
+	}
+	s.pos++
+	return s.data[s.pos-1] == '1', nil
+}
+
+// parsePathData parses the d attribute of a path element into absolute moves, lines and cubic curves.
+func parsePathData(d string) (pathData, error) {
+	path := pathData{}
+	scanner := &pathScanner{data: d}
+	var current, start, lastControl point
+	var command, previous byte
+
+	for scanner.skipSeparators(); !scanner.done(); scanner.skipSeparators() {
+		if c := scanner.data[scanner.pos]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0 {
+			command = c
+			scanner.pos++
+		} else if command == 0 || !scanner.hasNumber() {
+			return nil, fmt.Errorf("invalid path data at position %d", scanner.pos)
+		}
+
+		relative := command >= 'a'
+		var origin point
+		if relative {
+			origin = current
+		}
+
+		var err error
+		switch command | 0x20 {
+		case 'm':
+			var to point
+			if err = scanner.numbers(&to.x, &to.y); err == nil {
+				current = origin.add(to)
+				start = current
+				path.moveTo(current)
+				// Further coordinates after a move are lines
+				command = 'L'
+				if relative {
+					command = 'l'
+				}
+			}
+		case 'l':
+			var to point
+			if err = scanner.numbers(&to.x, &to.y); err == nil {
+				current = origin.add(to)
+				path.lineTo(current)
+			}
+		case 'h':
+			var x float64
+			if err = scanner.numbers(&x); err == nil {
+				current = point{origin.x + x, current.y}
+				path.lineTo(current)
+			}
+		case 'v':
+			var y float64
+			if err = scanner.numbers(&y); err == nil {
+				current = point{current.x, origin.y + y}
+				path.lineTo(current)
+			}
+		case 'c':
+			var c1, c2, to point
+			if err = scanner.numbers(&c1.x, &c1.y, &c2.x, &c2.y, &to.x, &to.y); err == nil {
+				lastControl = origin.add(c2)
+				current = origin.add(to)
+				path.cubicTo(origin.add(c1), lastControl, current)
+			}
+		case 's':
+			var c2, to point
+			if err = scanner.numbers(&c2.x, &c2.y, &to.x, &to.y); err == nil {
+				c1 := current
+				if previous|0x20 == 'c' || previous|0x20 == 's' {
+					c1 = current.mul(2).sub(lastControl)
+				}
+				lastControl = origin.add(c2)
+				current = origin.add(to)
+				path.cubicTo(c1, lastControl, current)
+			}
+		case 'q', 't':
+			var control, to point
+			if command|0x20 == 'q' {
+				err = scanner.numbers(&control.x, &control.y, &to.x, &to.y)
+				control = origin.add(control)
+			} else {
+				err = scanner.numbers(&to.x, &to.y)
+				control = current
+				if previous|0x20 == 'q' || previous|0x20 == 't' {
+					control = current.mul(2).sub(lastControl)
+				}
+			}
+			if err == nil {
+				to = origin.add(to)
+				path.cubicTo(current.lerp(control, 2.0/3), to.lerp(control, 2.0/3), to)
+				lastControl = control
+				current = to
+			}
+		case 'a':
+			var rx, ry, rotation float64
+			var large, sweep bool
+			var to point
+			if err = scanner.numbers(&rx, &ry, &rotation); err == nil {
+				if large, err = scanner.flag(); err == nil {
+					if sweep, err = scanner.flag(); err == nil {
+						if err = scanner.numbers(&to.x, &to.y); err == nil {
+							to = origin.add(to)
+							path.arcTo(current, rx, ry, rotation, large, sweep, to)
+							current = to
+						}
+					}
+				}
+			}
+		case 'z':
+			path.close()
+			current = start
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid path data: %w", err)
+		}
+		if len(path) > 0 && path[0].command != 'M' {
+			return nil, fmt.Errorf("invalid path data: must start with a move")
+		}
+		previous = command
+	}
+	return path, nil
+}
+
+// polyline is a subpath, flattened into straight lines.
+type polyline struct {
+	points []point
+	closed bool
+}
+
+// flatten transforms the path to pixel coordinates and converts its curves to short straight lines.
+func (p pathData) flatten(m matrix) []polyline {
+	lines := []polyline{}
+	var current *polyline
+	var position, start point
+	for _, segment := range p {
+		if current == nil && segment.command != 'M' {
+			// Anything drawn after a close without a move starts a new subpath from the same point
+			lines = append(lines, polyline{points: []point{position}})
+			current = &lines[len(lines)-1]
+		}
+		switch segment.command {
+		case 'M':
+			position = m.apply(segment.points[0])
+			start = position
+			lines = append(lines, polyline{points: []point{position}})
+			current = &lines[len(lines)-1]
+		case 'L':
+			position = m.apply(segment.points[0])
+			current.points = append(current.points, position)
+		case 'C':
+			p0, p1, p2, p3 := position, m.apply(segment.points[0]), m.apply(segment.points[1]), m.apply(segment.points[2])
+			length := p1.sub(p0).length() + p2.sub(p1).length() + p3.sub(p2).length()
+			steps := int(math.Min(100, math.Max(1, math.Ceil(math.Sqrt(length*2)))))
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / float64(steps)
+				a, b, c := p0.lerp(p1, t), p1.lerp(p2, t), p2.lerp(p3, t)
+				d, e := a.lerp(b, t), b.lerp(c, t)
+				current.points = append(current.points, d.lerp(e, t))
+			}
+			position = p3
+		case 'Z':
+			current.closed = true
+			position = start
+			current = nil
+		}
+	}
+	return lines
+}
+
+// strokeOutline returns polygons that together cover the stroke of the lines, all with the same orientation so they can be filled as a union.
+func strokeOutline(lines []polyline, width float64, lineCap, lineJoin string, miterLimit float64) [][]point {
+	half := width / 2
+	polygons := [][]point{}
+	add := func(polygon ...point) {
+		if polygonArea(polygon) < 0 {
+			for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+				polygon[i], polygon[j] = polygon[j], polygon[i]
+			}
+		}
+		polygons = append(polygons, polygon)
+	}
+	circle := func(center point) {
+		steps := int(math.Min(64, math.Max(8, math.Ceil(half*2))))
+		polygon := make([]point, steps)
+		for i := range polygon {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(steps))
+			polygon[i] = point{center.x + half*cos, center.y + half*sin}
+		}
+		add(polygon...)
+	}
+	square := func(center, direction point) {
+		d, n := direction.mul(half), direction.normal().mul(half)
+		add(center.add(d).add(n), center.add(d).sub(n), center.sub(d).sub(n), center.sub(d).add(n))
+	}
+
+	for _, line := range lines {
+		points := []point{}
+		for _, p := range line.points {
+			if len(points) == 0 || p != points[len(points)-1] {
+				points = append(points, p)
+			}
+		}
+		if line.closed && len(points) > 1 && points[0] == points[len(points)-1] {
+			points = points[:len(points)-1]
+		}
+
+		if len(points) == 1 {
+			// A zero length subpath only shows its caps
+			switch lineCap {
+			case "round":
+				circle(points[0])
+			case "square":
+				square(points[0], point{1, 0})
+			}
+			continue
+		}
+
+		count := len(points) - 1
+		if line.closed {
+			count = len(points)
+		}
+		direction := func(i int) point {
+			d := points[(i+1)%len(points)].sub(points[i])
+			return d.mul(1 / d.length())
+		}
+
+		for i := range count {
+			a, b := points[i], points[(i+1)%len(points)]
+			n := direction(i).normal().mul(half)
+			add(a.add(n), b.add(n), b.sub(n), a.sub(n))
+
+			if i == count-1 && !line.closed {
+				break
+			}
+			// Join this segment to the next one
+			d1, d2 := direction(i), direction((i+1)%count)
+			cross := d1.cross(d2)
+			if math.Abs(cross) < 1e-9 && d1.dot(d2) > 0 {
+				continue
+			}
+			if lineJoin == "round" {
+				circle(b)
+				continue
+			}
+			side := 1.0
+			if cross > 0 {
+				side = -1
+			}
+			o1, o2 := b.add(d1.normal().mul(half*side)), b.add(d2.normal().mul(half*side))
+			bisector := o1.sub(b).add(o2.sub(b))
+			if cos := bisector.length() / (2 * half); lineJoin != "bevel" && cos > 1e-9 && 1/cos <= miterLimit {
+				add(b, o1, b.add(bisector.mul(half/cos/bisector.length())), o2)
+			} else {
+				add(b, o1, o2)
+			}
+		}
+
+		if !line.closed {
+			first, last := direction(0), direction(len(points)-2)
+			switch lineCap {
+			case "round":
+				circle(points[0])
+				circle(points[len(points)-1])
+			case "square":
+				square(points[0], first)
+				square(points[len(points)-1], last)
+			}
+		}
+	}
+	return polygons
+}
+
+func polygonArea(polygon []point) float64 {
+	area := 0.0
+	for i, p := range polygon {
+		area += p.cross(polygon[(i+1)%len(polygon)])
+	}
+	return area / 2
+}
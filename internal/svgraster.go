@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/vector"
+)
+
+// rasterize fills the polygons into an alpha mask, covering the polygons' bounds within the given size.
+// Even-odd filling is used if evenOdd is true, otherwise the non-zero rule is used.
+// vector.Rasterizer only fills using the non-zero rule, so for even-odd filling each polygon is filled on its own,
+// and the areas covered by an even number of them are cleared. This is only correct for polygons that do not cross themselves.
+func rasterize(polygons [][]point, width, height int, evenOdd bool) *image.Alpha {
+	bounds := image.Rectangle{}
+	for _, polygon := range polygons {
+		for _, p := range polygon {
+			if math.IsNaN(p.x) || math.IsNaN(p.y) {
+				continue
+			}
+			bounds = bounds.Union(image.Rect(int(math.Floor(p.x)), int(math.Floor(p.y)), int(math.Ceil(p.x))+1, int(math.Ceil(p.y))+1))
+		}
+	}
+	bounds = bounds.Intersect(image.Rect(0, 0, width, height))
+
+	if !evenOdd || len(polygons) < 2 {
+		return fillPolygons(polygons, bounds)
+	}
+	mask := image.NewAlpha(bounds)
+	for _, polygon := range polygons {
+		fill := fillPolygons([][]point{polygon}, bounds)
+		for i, a := range fill.Pix {
+			b := int(mask.Pix[i])
+			mask.Pix[i] = uint8(b + int(a) - 2*b*int(a)/255)
+		}
+	}
+	return mask
+}
+
+// fillPolygons fills the polygons into an alpha mask with the given bounds, using the non-zero rule.
+func fillPolygons(polygons [][]point, bounds image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(bounds)
+	if bounds.Empty() {
+		return mask
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	origin := point{x: float64(bounds.Min.X), y: float64(bounds.Min.Y)}
+	for _, polygon := range polygons {
+		started := false
+		for _, p := range polygon {
+			if math.IsNaN(p.x) || math.IsNaN(p.y) {
+				continue
+			}
+			x, y := float32(p.x-origin.x), float32(p.y-origin.y)
+			if started {
+				z.LineTo(x, y)
+			} else {
+				z.MoveTo(x, y)
+				started = true
+			}
+		}
+		if started {
+			z.ClosePath()
+		}
+	}
+	z.Draw(mask, bounds, image.Opaque, image.Point{})
+	return mask
+}
+
+// crossesItself reports whether any two edges of the polygon cross, so that it cannot be filled using the even-odd rule.
+func crossesItself(polygon []point) bool {
+	n := len(polygon)
+	for i := 0; i < n; i++ {
+		a, b := polygon[i], polygon[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				// The closing edge joins the first one
+				continue
+			}
+			if segmentsCross(a, b, polygon[j], polygon[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsCross reports whether the segments from a to b and from c to d cross, not counting segments that only touch.
+func segmentsCross(a, b, c, d point) bool {
+	if math.Max(a.x, b.x) <= math.Min(c.x, d.x) || math.Max(c.x, d.x) <= math.Min(a.x, b.x) ||
+		math.Max(a.y, b.y) <= math.Min(c.y, d.y) || math.Max(c.y, d.y) <= math.Min(a.y, b.y) {
+		return false
+	}
+	return opposite(side(c, d, a), side(c, d, b)) && opposite(side(a, b, c), side(a, b, d))
+}
+
+// side is positive if p is to the left of the line from a to b, negative if it is to the right, and zero if it is on the line.
+func side(a, b, p point) float64 {
+	return (b.x-a.x)*(p.y-a.y) - (b.y-a.y)*(p.x-a.x)
+}
+
+func opposite(s, t float64) bool {
+	return (s > 0 && t < 0) || (s < 0 && t > 0)
+}
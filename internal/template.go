@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data available to templated sources, such as https://example.com/comics/{{.Year}}/{{.Month}}/{{.Day}}.png
+type TemplateData struct {
+	Time   time.Time
+	Year   string
+	Month  string
+	Day    string
+	Hour   string
+	Minute string
+	Date   string
+	Unix   int64
+	Width  int
+	Height int
+}
+
+func NewTemplateData(t time.Time, width, height int) TemplateData {
+	return TemplateData{
+		Time:   t,
+		Year:   t.Format("2006"),
+		Month:  t.Format("01"),
+		Day:    t.Format("02"),
+		Hour:   t.Format("15"),
+		Minute: t.Format("04"),
+		Date:   t.Format("2006-01-02"),
+		Unix:   t.Unix(),
+		Width:  width,
+		Height: height,
+	}
+}
+
+// IsTemplate returns true if the source contains template actions.
+func IsTemplate(source string) bool {
+	return strings.Contains(source, "{{")
+}
+
+// ExpandTemplate fills in the template actions in the source.
+func ExpandTemplate(source string, data TemplateData) (string, error) {
+	tmpl, err := template.New("source").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var expanded strings.Builder
+	if err := tmpl.Execute(&expanded, data); err != nil {
+		return "", err
+	}
+	return expanded.String(), nil
+}
+
+// ParseTimeOffset parses an offset from the current time: yesterday, today, tomorrow,
+// a number of days such as -1d, or a duration such as -6h or 90m.
+// Days are calendar days, so they are not affected by daylight saving time changes.
+func ParseTimeOffset(offset string) (days int, duration time.Duration, err error) {
+	switch offset {
+	case "", "today":
+		return 0, 0, nil
+	case "yesterday":
+		return -1, 0, nil
+	case "tomorrow":
+		return 1, 0, nil
+	}
+
+	if number, found := strings.CutSuffix(offset, "d"); found {
+		days, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid offset: \"%s\"", offset)
+		}
+		return days, 0, nil
+	}
+
+	duration, err = time.ParseDuration(offset)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid offset: \"%s\", must be yesterday, today, tomorrow, a number of days such as -1d, or a duration such as -6h", offset)
+	}
+	return 0, duration, nil
+}
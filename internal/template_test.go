@@ -0,0 +1,57 @@
+package internal_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+var _ = Describe("ExpandTemplate", func() {
+	data := internal.NewTemplateData(time.Date(2026, time.October, 8, 7, 5, 0, 0, time.UTC), 800, 480)
+
+	It("fills in the time and size", func() {
+		expanded, err := internal.ExpandTemplate("https://comics.example.com/{{.Year}}/{{.Month}}/{{.Day}}.png?w={{.Width}}&h={{.Height}}", data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expanded).To(Equal("https://comics.example.com/2026/10/08.png?w=800&h=480"))
+	})
+
+	It("can format the time", func() {
+		expanded, err := internal.ExpandTemplate(`https://webcam.example.com/{{.Time.Format "20060102-1504"}}.jpg`, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expanded).To(Equal("https://webcam.example.com/20261008-0705.jpg"))
+	})
+
+	When("the template refers to an unknown field", func() {
+		It("returns an error", func() {
+			_, err := internal.ExpandTemplate("https://example.com/{{.Week}}.png", data)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("can't evaluate field Week"))
+		})
+	})
+})
+
+var _ = Describe("ParseTimeOffset", func() {
+	DescribeTable("valid offsets",
+		func(offset string, expectedDays int, expectedDuration time.Duration) {
+			days, duration, err := internal.ParseTimeOffset(offset)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(days).To(Equal(expectedDays))
+			Expect(duration).To(Equal(expectedDuration))
+		},
+		Entry("none", "", 0, time.Duration(0)),
+		Entry("today", "today", 0, time.Duration(0)),
+		Entry("yesterday", "yesterday", -1, time.Duration(0)),
+		Entry("tomorrow", "tomorrow", 1, time.Duration(0)),
+		Entry("days", "-7d", -7, time.Duration(0)),
+		Entry("duration", "-6h30m", 0, -6*time.Hour-30*time.Minute),
+	)
+
+	It("rejects invalid offsets", func() {
+		_, _, err := internal.ParseTimeOffset("last week")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("invalid offset: \"last week\""))
+	})
+})
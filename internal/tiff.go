@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"golang.org/x/image/tiff"
+)
+
+// maxTiffPages stops a malformed file with a loop of pages from being followed forever.
+const maxTiffPages = 10000
+
+// decodeTiffPage decodes one page of a multi-page TIFF, counting from 0.
+func decodeTiffPage(r io.Reader, page int, maxPixels int64) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err = selectTiffPage(data, page)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := tiff.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPixels(config, maxPixels); err != nil {
+		return nil, err
+	}
+	return tiff.Decode(bytes.NewReader(data))
+}
+
+// selectTiffPage returns a copy of the TIFF data with the header pointing at the given page, since the decoder only reads the first page.
+func selectTiffPage(data []byte, page int) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("TIFF data is too short")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if string(data[0:2]) == "MM" {
+		order = binary.BigEndian
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, &UnsupportedFormatError{Format: "bigtiff"}
+	}
+
+	var pages []uint32
+	for offset := order.Uint32(data[4:8]); offset != 0 && len(pages) < maxTiffPages; {
+		if int64(offset)+2 > int64(len(data)) {
+			return nil, fmt.Errorf("TIFF page %d is outside of the data", len(pages))
+		}
+		pages = append(pages, offset)
+		entries := int64(order.Uint16(data[offset : offset+2]))
+		next := int64(offset) + 2 + entries*12
+		if next+4 > int64(len(data)) {
+			return nil, fmt.Errorf("TIFF page %d is outside of the data", len(pages)-1)
+		}
+		offset = order.Uint32(data[next : next+4])
+	}
+
+	if page >= len(pages) {
+		return nil, fmt.Errorf("page %d is out of range, the TIFF has %d pages", page, len(pages))
+	}
+	selected := bytes.Clone(data)
+	order.PutUint32(selected[4:8], pages[page])
+	return selected, nil
+}
@@ -0,0 +1,7 @@
+package internal
+
+import "time"
+
+var Now = time.Now
+
+var Sleep = time.Sleep
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// LoadTLSConfig returns the TLS settings for fetching images, or nil if the defaults should be used.
+// The CA file is added to the system's trusted certificates, rather than replacing them.
+func LoadTLSConfig(caFile, certFile, keyFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" && !insecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file does not contain any PEM certificates: %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("a client certificate and key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// ParseProxyURL checks that the proxy is an http, https or socks5 URL.
+func ParseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case SchemeHttp, SchemeHttps, "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL: \"%s\", must be an http, https or socks5 URL", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: \"%s\", missing a host", proxy)
+	}
+	return u, nil
+}
+
+// configureTransport applies the TLS and proxy settings to the transport.
+func (o *HttpOptions) configureTransport(transport *http.Transport) error {
+	tlsConfig, err := LoadTLSConfig(o.CAFile, o.ClientCertFile, o.ClientKeyFile, o.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if o.InsecureSkipVerify {
+		Logger.Printf("WARNING: TLS certificate verification is disabled. The server's identity is not checked, and anyone on the network can tamper with the image. Only use insecureSkipVerify for testing.")
+	}
+
+	if o.Proxy != "" {
+		proxy, err := ParseProxyURL(o.Proxy)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package internal_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(template *x509.Certificate, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	return &testCertificate{cert: cert, key: key}
+}
+
+func (c *testCertificate) writeFiles(dir, name string) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600)).To(Succeed())
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	Expect(err).ToNot(HaveOccurred())
+	keyFile := filepath.Join(dir, name+".key")
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).To(Succeed())
+	return certFile, keyFile
+}
+
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+var _ = Describe("TLS and proxy settings", func() {
+	var (
+		dir    string
+		logs   *gbytes.Buffer
+		caFile string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		logs = gbytes.NewBuffer()
+		internal.Logger = log.New(io.MultiWriter(logs, GinkgoWriter), "", 0)
+	})
+
+	Context("with a server that uses a private CA and requires client certificates", func() {
+		var (
+			server            *httptest.Server
+			clientCertFile    string
+			clientKeyFile     string
+			untrustedCertFile string
+			untrustedKeyFile  string
+		)
+
+		BeforeEach(func() {
+			ca := newTestCertificate(&x509.Certificate{
+				Subject:               pkix.Name{CommonName: "Test CA"},
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageCertSign,
+			}, nil)
+			caFile, _ = ca.writeFiles(dir, "ca")
+
+			serverCert := newTestCertificate(&x509.Certificate{
+				Subject:     pkix.Name{CommonName: "images.internal"},
+				IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}, ca)
+			clientCert := newTestCertificate(&x509.Certificate{
+				Subject:     pkix.Name{CommonName: "picture-frame"},
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}, ca)
+			clientCertFile, clientKeyFile = clientCert.writeFiles(dir, "client")
+			untrusted := newTestCertificate(&x509.Certificate{
+				Subject:     pkix.Name{CommonName: "stranger"},
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}, nil)
+			untrustedCertFile, untrustedKeyFile = untrusted.writeFiles(dir, "untrusted")
+
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(ca.cert)
+			server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("image for " + r.TLS.PeerCertificates[0].Subject.CommonName))
+			}))
+			server.TLS = &tls.Config{
+				Certificates: []tls.Certificate{serverCert.tlsCertificate()},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    clientCAs,
+			}
+			server.StartTLS()
+			DeferCleanup(server.Close)
+		})
+
+		It("fetches the image with the CA and client certificate", func() {
+			res, err := internal.HttpGet(server.URL+"/image.jpg", &internal.HttpOptions{
+				CAFile:         caFile,
+				ClientCertFile: clientCertFile,
+				ClientKeyFile:  clientKeyFile,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer res.Body.Close()
+			data, err := io.ReadAll(res.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("image for picture-frame"))
+		})
+
+		It("does not trust the server without the CA", func() {
+			_, err := internal.HttpGet(server.URL+"/image.jpg", &internal.HttpOptions{
+				ClientCertFile: clientCertFile,
+				ClientKeyFile:  clientKeyFile,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("certificate signed by unknown authority"))
+		})
+
+		It("is rejected by the server with an untrusted client certificate", func() {
+			_, err := internal.HttpGet(server.URL+"/image.jpg", &internal.HttpOptions{
+				CAFile:         caFile,
+				ClientCertFile: untrustedCertFile,
+				ClientKeyFile:  untrustedKeyFile,
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("certificate verification is disabled", func() {
+		It("fetches the image and logs a warning", func() {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("image data"))
+			}))
+			DeferCleanup(server.Close)
+
+			res, err := internal.HttpGet(server.URL+"/image.jpg", &internal.HttpOptions{InsecureSkipVerify: true})
+			Expect(err).ToNot(HaveOccurred())
+			defer res.Body.Close()
+			Expect(logs).To(gbytes.Say("WARNING: TLS certificate verification is disabled"))
+		})
+	})
+
+	When("a proxy is configured", func() {
+		It("sends the request through the proxy", func() {
+			var proxied *http.Request
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied = r
+				_, _ = w.Write([]byte("proxied image data"))
+			}))
+			DeferCleanup(proxy.Close)
+
+			res, err := internal.HttpGet("http://images.example.com/image.jpg", &internal.HttpOptions{Proxy: proxy.URL})
+			Expect(err).ToNot(HaveOccurred())
+			defer res.Body.Close()
+			data, err := io.ReadAll(res.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("proxied image data"))
+			Expect(proxied.Host).To(Equal("images.example.com"))
+			Expect(proxied.RequestURI).To(Equal("http://images.example.com/image.jpg"))
+		})
+	})
+
+	Describe("LoadTLSConfig", func() {
+		It("requires a client certificate and key together", func() {
+			_, err := internal.LoadTLSConfig("", filepath.Join(dir, "client.crt"), "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("a client certificate and key must be used together"))
+		})
+
+		It("requires the CA file to contain certificates", func() {
+			caFile = filepath.Join(dir, "ca.crt")
+			Expect(os.WriteFile(caFile, bytes.Repeat([]byte("not a certificate\n"), 3), 0600)).To(Succeed())
+
+			_, err := internal.LoadTLSConfig(caFile, "", "", false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("CA file does not contain any PEM certificates: " + caFile))
+		})
+	})
+
+	Describe("ParseProxyURL", func() {
+		It("rejects unsupported schemes", func() {
+			_, err := internal.ParseProxyURL("ftp://proxy.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("invalid proxy URL: \"ftp://proxy.example.com\", must be an http, https or socks5 URL"))
+		})
+	})
+})
@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type ArchiveType struct {
+	Members string `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+func (a *ArchiveType) options() *internal.DirectoryOptions {
+	options := &internal.DirectoryOptions{Recursive: true}
+	if a != nil && a.Members != "" {
+		options.Include = []string{a.Members}
+	}
+	return options
+}
+
+func (a *ArchiveType) Validate() error {
+	if err := internal.ValidateGlob(a.Members); err != nil {
+		return fmt.Errorf("invalid members pattern \"%s\": %w", a.Members, err)
+	}
+	return nil
+}
+
+// openArchive opens a local archive in place, or downloads a remote archive into memory.
+func (c *Config) openArchive(source string) (io.ReaderAt, int64, func(), error) {
+	if internal.SourceScheme(source) == internal.SchemeFile {
+		f, size, err := internal.OpenArchive(source)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return f, size, func() { _ = f.Close() }, nil
+	}
+
+	data, err := c.fetchDocument(source)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return bytes.NewReader(data), int64(len(data)), func() {}, nil
+}
+
+// fetchArchiveImage picks an image from the files in a zip or tar archive.
+func (c *Config) fetchArchiveImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	archive, size, closeArchive, err := c.openArchive(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch archive (%s): %w", internal.DescribeSource(source), err)
+	}
+	defer closeArchive()
+
+	members, err := internal.ListArchiveImages(archive, size, c.Archive.options())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list images in archive (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	data, im, member, err := c.fetchOneOf("archive:"+info.Source, members, func(member string) ([]byte, image.Image, error) {
+		data, err := internal.ReadArchiveMember(archive, size, member, c.Limits.maxBytes())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read image (%s in %s): %w", member, internal.DescribeSource(source), err)
+		}
+		im, err := internal.DecodeImage(bytes.NewReader(data), c.decodeOptions())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode image (%s in %s): %w", member, internal.DescribeSource(source), err)
+		}
+		return data, im, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pick an image from archive (%s): %w", internal.DescribeSource(source), err)
+	}
+	if im == nil {
+		return nil, nil, &internal.NoArchiveImagesError{Archive: internal.DescribeSource(source)}
+	}
+	info.Location = source + "#" + member
+	return data, im, nil
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"math"
 	"os"
+	"path/filepath"
 
 	"golang.org/x/image/draw"
 
@@ -31,22 +32,74 @@ type BackgroundType struct {
 }
 
 type Config struct {
-	Source     string          `json:"source" yaml:"source"`
-	Scale      string          `json:"scale" yaml:"scale"`
-	Background *BackgroundType `json:"background,omitempty" yaml:"background,omitempty"`
+	Type            string          `json:"type,omitempty" yaml:"type,omitempty"`
+	Source          string          `json:"source" yaml:"source"`
+	FallbackSources []string        `json:"fallbackSources,omitempty" yaml:"fallbackSources,omitempty"`
+	Sources         []string        `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Template        *TemplateType   `json:"template,omitempty" yaml:"template,omitempty"`
+	Rotation        *RotationType   `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	Directory       *DirectoryType  `json:"directory,omitempty" yaml:"directory,omitempty"`
+	Feed            *FeedType       `json:"feed,omitempty" yaml:"feed,omitempty"`
+	Json            *JsonType       `json:"json,omitempty" yaml:"json,omitempty"`
+	Html            *HtmlType       `json:"html,omitempty" yaml:"html,omitempty"`
+	Archive         *ArchiveType    `json:"archive,omitempty" yaml:"archive,omitempty"`
+	Mjpeg           *MjpegType      `json:"mjpeg,omitempty" yaml:"mjpeg,omitempty"`
+	Scale           string          `json:"scale" yaml:"scale"`
+	Background      *BackgroundType `json:"background,omitempty" yaml:"background,omitempty"`
+	Http            *HttpType       `json:"http,omitempty" yaml:"http,omitempty"`
+	Limits          *LimitsType     `json:"limits,omitempty" yaml:"limits,omitempty"`
+	Gif             *GifType        `json:"gif,omitempty" yaml:"gif,omitempty"`
+	Tiff            *TiffType       `json:"tiff,omitempty" yaml:"tiff,omitempty"`
+	Jpeg            *JpegType       `json:"jpeg,omitempty" yaml:"jpeg,omitempty"`
+	Cache           *CacheType      `json:"cache,omitempty" yaml:"cache,omitempty"`
+	S3              *S3Type         `json:"s3,omitempty" yaml:"s3,omitempty"`
+
+	LastKnownGood *LastKnownGoodType `json:"lastKnownGood,omitempty" yaml:"lastKnownGood,omitempty"`
+
+	info *SourceInfo
+	// path is the config file that was read, so that each config keeps its own place in a slideshow
+	path string
+	// size is the size of the image being generated, so that SVG images can be drawn at that size
+	size image.Point
+}
+
+func (c *Config) httpOptions() *internal.HttpOptions {
+	options := c.Http.options()
+	if c.Cache != nil || c.S3 != nil {
+		if options == nil {
+			options = &internal.HttpOptions{Retries: internal.DefaultRetries}
+		}
+		if c.Cache != nil {
+			options.Cache = c.Cache.options()
+		}
+		options.S3 = c.S3.options()
+	}
+	return options
 }
 
 func (c *Config) GenerateImage(width, height int) (image.Image, error) {
-	res, err := internal.HttpGet(c.Source)
+	c.size = image.Pt(width, height)
+	sources, err := c.sourcesToTry()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch image (%s): %w", c.Source, err)
+		return nil, err
 	}
 
-	im, err := internal.DecodeImage(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image (%s): %w", c.Source, err)
+	im, err := c.fetchFirstImage(sources, width, height)
+	if err != nil && c.LastKnownGood.isEnabled() {
+		im, err = c.useLastKnownGood(sources, err)
+	}
+	if im == nil {
+		return nil, err
 	}
 
+	scaled, scaleErr := c.scaleImage(width, height, im)
+	if scaleErr != nil {
+		return nil, scaleErr
+	}
+	return scaled, err
+}
+
+func (c *Config) scaleImage(width, height int, im image.Image) (image.Image, error) {
 	switch c.Scale {
 	case ScaleContain:
 		return c.generateContainedImage(width, height, im)
@@ -113,9 +166,88 @@ func (c *Config) generateResizedImage(width, height int, im image.Image) (image.
 }
 
 func (c *Config) Validate() error {
-	if c.Source == "" {
+	if c.Source == "" && len(c.Sources) == 0 {
 		return fmt.Errorf("missing image source")
 	}
+	if c.Source != "" && len(c.Sources) > 0 {
+		return fmt.Errorf("only one of source and sources can be used")
+	}
+	if len(c.FallbackSources) > 0 && len(c.Sources) > 0 {
+		return fmt.Errorf("fallbackSources cannot be used with sources")
+	}
+	if c.Template != nil {
+		if err := c.Template.Validate(); err != nil {
+			return fmt.Errorf("invalid template settings: %w", err)
+		}
+	}
+
+	sources := c.Sources
+	if len(sources) == 0 {
+		sources = c.sourceChain()
+	}
+	for _, source := range sources {
+		if source == "" {
+			return fmt.Errorf("missing image source")
+		}
+		if err := c.validateSource(source); err != nil {
+			if len(sources) > 1 {
+				return fmt.Errorf("invalid image source (%s): %w", internal.DescribeSource(source), err)
+			}
+			return fmt.Errorf("invalid image source: %w", err)
+		}
+	}
+
+	switch c.Type {
+	case "", SourceTypeImage, SourceTypeFeed, SourceTypeJson, SourceTypeHtml, SourceTypeArchive, SourceTypeMjpeg:
+	default:
+		return fmt.Errorf("type value is invalid: \"%s\", must be one of image, feed, json, html, archive, mjpeg", c.Type)
+	}
+
+	if c.Type == SourceTypeJson && c.Json == nil {
+		return fmt.Errorf("json settings are required when type is json")
+	}
+
+	if c.Rotation != nil {
+		if err := c.Rotation.Validate(); err != nil {
+			return fmt.Errorf("invalid rotation: %w", err)
+		}
+	}
+
+	if c.Directory != nil {
+		if err := c.Directory.Validate(); err != nil {
+			return fmt.Errorf("invalid directory settings: %w", err)
+		}
+	}
+
+	if c.Feed != nil {
+		if err := c.Feed.Validate(); err != nil {
+			return fmt.Errorf("invalid feed settings: %w", err)
+		}
+	}
+
+	if c.Json != nil {
+		if err := c.Json.Validate(); err != nil {
+			return fmt.Errorf("invalid json settings: %w", err)
+		}
+	}
+
+	if c.Html != nil {
+		if err := c.Html.Validate(); err != nil {
+			return fmt.Errorf("invalid html settings: %w", err)
+		}
+	}
+
+	if c.Archive != nil {
+		if err := c.Archive.Validate(); err != nil {
+			return fmt.Errorf("invalid archive settings: %w", err)
+		}
+	}
+
+	if c.Mjpeg != nil {
+		if err := c.Mjpeg.Validate(); err != nil {
+			return fmt.Errorf("invalid mjpeg settings: %w", err)
+		}
+	}
 
 	if c.Scale != ScaleResize &&
 		c.Scale != ScaleContain &&
@@ -127,10 +259,65 @@ func (c *Config) Validate() error {
 	if err := backgroundConfig.Validate(); err != nil {
 		return fmt.Errorf("invalid background: %w", err)
 	}
+
+	if c.Http != nil {
+		if err := c.Http.Validate(); err != nil {
+			return fmt.Errorf("invalid http settings: %w", err)
+		}
+	}
+
+	if c.Limits != nil {
+		if err := c.Limits.Validate(); err != nil {
+			return fmt.Errorf("invalid limits: %w", err)
+		}
+	}
+
+	if c.Gif != nil {
+		if err := c.Gif.Validate(); err != nil {
+			return fmt.Errorf("invalid gif settings: %w", err)
+		}
+	}
+
+	if c.Tiff != nil {
+		if err := c.Tiff.Validate(); err != nil {
+			return fmt.Errorf("invalid tiff settings: %w", err)
+		}
+	}
+
+	if c.Cache != nil {
+		if err := c.Cache.Validate(); err != nil {
+			return fmt.Errorf("invalid cache settings: %w", err)
+		}
+	}
+
+	if c.S3 != nil {
+		if err := c.S3.Validate(); err != nil {
+			return fmt.Errorf("invalid s3 settings: %w", err)
+		}
+	}
 	return nil
 }
 
-func ParseConfig(path string) (*Config, error) {
+// validateSource checks a source, after expanding it with an example size if it is a template.
+func (c *Config) validateSource(source string) error {
+	if source == StdinSource {
+		if c.Type != "" && c.Type != SourceTypeImage {
+			return fmt.Errorf("stdin can only be used for images, not %s sources", c.Type)
+		}
+		return nil
+	}
+	if internal.IsTemplate(source) {
+		expanded, err := c.expandSource(source, exampleWidth, exampleHeight)
+		if err != nil {
+			return err
+		}
+		source = expanded
+	}
+	return internal.ValidateSource(source)
+}
+
+// ReadConfig reads the config file, without validating it.
+func ReadConfig(path string) (*Config, error) {
 	configData, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image config file: %w", err)
@@ -141,6 +328,13 @@ func ParseConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse image config file: %w", err)
 	}
+	if config == nil {
+		config = &Config{}
+	}
+	config.path = path
+	if absolute, err := filepath.Abs(path); err == nil {
+		config.path = absolute
+	}
 
 	if config.Background == nil {
 		config.Background = &BackgroundType{
@@ -150,6 +344,21 @@ func ParseConfig(path string) (*Config, error) {
 	if config.Background.Color == "" {
 		config.Background.Color = "white"
 	}
+	return config, nil
+}
+
+// UseStdin replaces the configured sources, so that the image is read from standard input.
+func (c *Config) UseStdin() {
+	c.Source = StdinSource
+	c.FallbackSources = nil
+	c.Sources = nil
+}
+
+func ParseConfig(path string) (*Config, error) {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
 
 	err = config.Validate()
 	if err != nil {
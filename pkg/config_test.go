@@ -1,11 +1,21 @@
 package pkg_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"image"
+	"io"
+	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -17,9 +27,23 @@ import (
 	"github.com/petewall/eink-radiator-image-source-image/pkg"
 )
 
+type responseBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *responseBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
 var _ = Describe("Config", func() {
 	Describe("GenerateImage", func() {
 		var (
+			res          *http.Response
+			body         *responseBody
 			httpGetter   *internalfakes.FakeHttpGetter
 			imageDecoder *internalfakes.FakeImageDecoder
 
@@ -27,6 +51,9 @@ var _ = Describe("Config", func() {
 			fetchedImage    *image.RGBA
 			returnedImage   *image.RGBA
 
+			lastKnownGoodSaver  *internalfakes.FakeLastKnownGoodSaver
+			lastKnownGoodLoader *internalfakes.FakeLastKnownGoodLoader
+
 			makeBackground *internalfakes.FakeBackgroundMaker
 			drawer         *internalfakes.FakeDrawer
 			newImage       *internalfakes.FakeImageMaker
@@ -39,11 +66,25 @@ var _ = Describe("Config", func() {
 			imageDecoder.Returns(fetchedImage, nil)
 			internal.DecodeImage = imageDecoder.Spy
 
-			res := &http.Response{}
+			body = &responseBody{Reader: bytes.NewReader(pngHeader)}
+			res = &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"image/png"}},
+				Body:       body,
+			}
 			httpGetter = &internalfakes.FakeHttpGetter{}
 			httpGetter.Returns(res, nil)
 			internal.HttpGet = httpGetter.Spy
 
+			internal.Logger = log.New(GinkgoWriter, "", 0)
+
+			lastKnownGoodSaver = &internalfakes.FakeLastKnownGoodSaver{}
+			internal.SaveLastKnownGood = lastKnownGoodSaver.Spy
+			lastKnownGoodLoader = &internalfakes.FakeLastKnownGoodLoader{}
+			lastKnownGoodLoader.Returns(nil, time.Time{}, errors.New("no such file"))
+			internal.LoadLastKnownGood = lastKnownGoodLoader.Spy
+
 			backgroundImage = image.NewRGBA(image.Rect(0, 0, 300, 200))
 			makeBackground = &internalfakes.FakeBackgroundMaker{}
 			makeBackground.Returns(backgroundImage)
@@ -76,11 +117,19 @@ var _ = Describe("Config", func() {
 
 				By("fetching the image", func() {
 					Expect(httpGetter.CallCount()).To(Equal(1))
-					Expect(httpGetter.ArgsForCall(0)).To(Equal("https://www.example.com/link.jpg"))
+					source, options := httpGetter.ArgsForCall(0)
+					Expect(source).To(Equal("https://www.example.com/link.jpg"))
+					Expect(options).To(BeNil())
 				})
 
 				By("decoding the image", func() {
 					Expect(imageDecoder.CallCount()).To(Equal(1))
+					_, options := imageDecoder.ArgsForCall(0)
+					Expect(options.MaxPixels).To(Equal(int64(internal.DefaultMaxPixels)))
+				})
+
+				By("closing the response body", func() {
+					Expect(body.closed).To(BeTrue())
 				})
 
 				By("returning a resized version of the image", func() {
@@ -125,7 +174,9 @@ var _ = Describe("Config", func() {
 
 				By("fetching the image", func() {
 					Expect(httpGetter.CallCount()).To(Equal(1))
-					Expect(httpGetter.ArgsForCall(0)).To(Equal("https://www.example.com/link.jpg"))
+					source, options := httpGetter.ArgsForCall(0)
+					Expect(source).To(Equal("https://www.example.com/link.jpg"))
+					Expect(options).To(BeNil())
 				})
 
 				By("decoding the image", func() {
@@ -207,7 +258,9 @@ var _ = Describe("Config", func() {
 
 				By("fetching the image", func() {
 					Expect(httpGetter.CallCount()).To(Equal(1))
-					Expect(httpGetter.ArgsForCall(0)).To(Equal("https://www.example.com/link.jpg"))
+					source, options := httpGetter.ArgsForCall(0)
+					Expect(source).To(Equal("https://www.example.com/link.jpg"))
+					Expect(options).To(BeNil())
 				})
 
 				By("decoding the image", func() {
@@ -250,161 +303,1747 @@ var _ = Describe("Config", func() {
 			})
 		})
 
-		Context("unknown scale type", func() {
-			It("returns an error", func() {
+		Context("with http settings", func() {
+			It("passes the settings along when fetching the image", func() {
 				config := &pkg.Config{
 					Source: "https://www.example.com/link.jpg",
-					Scale:  "smoothjazz",
+					Scale:  "resize",
 					Background: &pkg.BackgroundType{
 						Color: "red",
 					},
+					Http: &pkg.HttpType{
+						Headers:   map[string]string{"X-Api-Key": "secret"},
+						UserAgent: "eink-radiator",
+						BasicAuth: &pkg.BasicAuthType{
+							Username: "link",
+							Password: "triforce",
+						},
+					},
 				}
 
-				_, err := config.GenerateImage(200, 300)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("unknown image scale type: smoothjazz"))
-			})
-		})
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
 
-		When("getting the image fails", func() {
-			BeforeEach(func() {
-				httpGetter.Returns(nil, errors.New("http get failed"))
+				Expect(httpGetter.CallCount()).To(Equal(1))
+				_, options := httpGetter.ArgsForCall(0)
+				Expect(options.Headers).To(Equal(map[string]string{"X-Api-Key": "secret"}))
+				Expect(options.UserAgent).To(Equal("eink-radiator"))
+				Expect(options.Username).To(Equal("link"))
+				Expect(options.Password).To(Equal("triforce"))
+				Expect(options.BearerToken).To(BeEmpty())
+				Expect(options.Retries).To(Equal(internal.DefaultRetries))
 			})
+		})
 
-			It("returns an error", func() {
+		Context("with a cache", func() {
+			It("passes the cache settings along when fetching the image", func() {
 				config := &pkg.Config{
 					Source: "https://www.example.com/link.jpg",
-					Scale:  "cover",
+					Scale:  "resize",
 					Background: &pkg.BackgroundType{
 						Color: "red",
 					},
+					Cache: &pkg.CacheType{
+						Directory: "/var/cache/images",
+						MaxSize:   1024,
+						MaxAge:    "5m",
+					},
 				}
 
-				_, err := config.GenerateImage(200, 300)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): http get failed"))
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(httpGetter.CallCount()).To(Equal(1))
+				_, options := httpGetter.ArgsForCall(0)
+				Expect(options.Retries).To(Equal(internal.DefaultRetries))
+				Expect(options.Cache).To(Equal(&internal.CacheOptions{
+					Directory: "/var/cache/images",
+					MaxSize:   1024,
+					MaxAge:    5 * time.Minute,
+				}))
 			})
 		})
 
-		When("decoding the image fails", func() {
+		Context("with a list of sources", func() {
+			var config *pkg.Config
+
 			BeforeEach(func() {
-				imageDecoder.Returns(nil, errors.New("image decoding failed"))
+				config = &pkg.Config{
+					Source: "https://cdn.example.com/link.jpg",
+					FallbackSources: []string{
+						"https://mirror.example.com/link.jpg",
+						"/home/pi/link.jpg",
+					},
+					Scale: "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
 			})
 
-			It("returns an error", func() {
-				config := &pkg.Config{
-					Source: "https://www.example.com/link.jpg",
-					Scale:  "cover",
+			It("uses the first source that works", func() {
+				httpGetter.Stub = func(source string, _ *internal.HttpOptions) (*http.Response, error) {
+					if source == "https://cdn.example.com/link.jpg" {
+						return nil, errors.New("cdn is down")
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+				imageDecoder.ReturnsOnCall(0, nil, errors.New("mirror image is corrupt"))
+
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(3))
+				source, _ := httpGetter.ArgsForCall(0)
+				Expect(source).To(Equal("https://cdn.example.com/link.jpg"))
+				source, _ = httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal("https://mirror.example.com/link.jpg"))
+				source, _ = httpGetter.ArgsForCall(2)
+				Expect(source).To(Equal("/home/pi/link.jpg"))
+				Expect(imageDecoder.CallCount()).To(Equal(2))
+			})
+
+			It("stops after the first source that works", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(httpGetter.CallCount()).To(Equal(1))
+			})
+
+			When("every source fails", func() {
+				BeforeEach(func() {
+					httpGetter.ReturnsOnCall(0, nil, errors.New("cdn is down"))
+					httpGetter.ReturnsOnCall(1, nil, errors.New("mirror is down"))
+					httpGetter.ReturnsOnCall(2, nil, errors.New("file is missing"))
+				})
+
+				It("returns an error that combines all of the errors", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("all 3 image sources failed: " +
+						"failed to fetch image (https://cdn.example.com/link.jpg): cdn is down; " +
+						"failed to fetch image (https://mirror.example.com/link.jpg): mirror is down; " +
+						"failed to fetch image (/home/pi/link.jpg): file is missing"))
+
+					var sourcesErr *pkg.SourcesError
+					Expect(errors.As(err, &sourcesErr)).To(BeTrue())
+					Expect(sourcesErr.Errors).To(HaveLen(3))
+				})
+			})
+		})
+
+		Context("with a slideshow", func() {
+			var (
+				config  *pkg.Config
+				fetched func() []string
+			)
+
+			BeforeEach(func() {
+				httpGetter.Stub = func(string, *internal.HttpOptions) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+				newImage.Returns(returnedImage)
+
+				config = &pkg.Config{
+					Sources: []string{
+						"https://www.example.com/link.jpg",
+						"https://www.example.com/zelda.jpg",
+						"https://www.example.com/ganon.jpg",
+					},
+					Scale: "resize",
 					Background: &pkg.BackgroundType{
 						Color: "red",
 					},
+					Rotation: &pkg.RotationType{
+						StateFile: filepath.Join(GinkgoT().TempDir(), "state", "rotation.json"),
+					},
 				}
 
-				_, err := config.GenerateImage(200, 300)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(Equal("failed to decode image (https://www.example.com/link.jpg): image decoding failed"))
+				fetched = func() []string {
+					sources := []string{}
+					for i := 0; i < httpGetter.CallCount(); i++ {
+						source, _ := httpGetter.ArgsForCall(i)
+						sources = append(sources, source)
+					}
+					return sources
+				}
 			})
-		})
-	})
-})
 
-var _ = Describe("ParseConfig", func() {
-	var (
-		configFile         *os.File
-		configFileContents []byte
-	)
+			It("shows each image in order across runs", func() {
+				for i := 0; i < 4; i++ {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+				}
 
-	JustBeforeEach(func() {
-		var err error
-		configFile, err = os.CreateTemp("", "blank-config.yaml")
-		Expect(err).ToNot(HaveOccurred())
-		_, err = configFile.Write(configFileContents)
-		Expect(err).ToNot(HaveOccurred())
-	})
+				Expect(fetched()).To(Equal([]string{
+					"https://www.example.com/link.jpg",
+					"https://www.example.com/zelda.jpg",
+					"https://www.example.com/ganon.jpg",
+					"https://www.example.com/link.jpg",
+				}))
+				Expect(config.Rotation.StateFile).To(BeARegularFile())
+			})
 
-	BeforeEach(func() {
-		config := pkg.Config{
-			Source: "https://www.example.com/link.jpg",
-			Scale:  "contain",
-			Background: &pkg.BackgroundType{
-				Color: "red",
-			},
-		}
-		var err error
-		configFileContents, err = yaml.Marshal(config)
-		Expect(err).ToNot(HaveOccurred())
-	})
+			It("keeps its place when the list of images in the config file changes", func() {
+				configFile := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+				data, err := yaml.Marshal(config)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.WriteFile(configFile, data, 0644)).To(Succeed())
+				config, err = pkg.ReadConfig(configFile)
+				Expect(err).ToNot(HaveOccurred())
 
-	AfterEach(func() {
-		Expect(os.Remove(configFile.Name())).To(Succeed())
-	})
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
 
-	It("parses the image config file", func() {
-		config, err := pkg.ParseConfig(configFile.Name())
-		Expect(err).ToNot(HaveOccurred())
-		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
-		Expect(config.Scale).To(Equal("contain"))
-		Expect(config.Background.Color).To(Equal("red"))
-	})
+				config.Sources = append([]string{"https://www.example.com/epona.jpg"}, config.Sources...)
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
 
-	Context("config file is json formatted", func() {
-		BeforeEach(func() {
-			config := pkg.Config{
-				Source: "https://www.example.com/impa.jpg",
-				Scale:  "cover",
-				Background: &pkg.BackgroundType{
-					Color: "blue",
-				},
-			}
-			var err error
-			configFileContents, err = json.Marshal(config)
-			Expect(err).ToNot(HaveOccurred())
-		})
+				config.Sources = slices.DeleteFunc(config.Sources, func(source string) bool {
+					return source == "https://www.example.com/zelda.jpg"
+				})
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
 
-		It("parses just fine", func() {
-			config, err := pkg.ParseConfig(configFile.Name())
-			Expect(err).ToNot(HaveOccurred())
-			Expect(config.Source).To(Equal("https://www.example.com/impa.jpg"))
-			Expect(config.Scale).To(Equal("cover"))
-			Expect(config.Background.Color).To(Equal("blue"))
-		})
-	})
+				Expect(fetched()).To(Equal([]string{
+					"https://www.example.com/link.jpg",
+					"https://www.example.com/zelda.jpg",
+					"https://www.example.com/ganon.jpg",
+				}))
+			})
 
-	When("reading the config file fails", func() {
-		It("returns an error", func() {
-			_, err := pkg.ParseConfig("this file does not exist")
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("failed to read image config file: open this file does not exist: no such file or directory"))
-		})
-	})
+			It("removes the positions of rotations that have not been used recently", func() {
+				Expect(os.MkdirAll(filepath.Dir(config.Rotation.StateFile), 0755)).To(Succeed())
+				old := time.Now().Add(-60 * 24 * time.Hour).Format(time.RFC3339)
+				state := `{"sequential:sources:1a2b3c":{"next":1,"last":"https://www.example.com/old.jpg"},` +
+					`"sequential:feed:https://www.example.com/old.xml":{"next":2,"used":"` + old + `"}}`
+				Expect(os.WriteFile(config.Rotation.StateFile, []byte(state), 0644)).To(Succeed())
 
-	When("parsing the config file fails", func() {
-		BeforeEach(func() {
-			configFileContents = []byte("this is invalid yaml!")
-		})
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
 
-		It("returns an error", func() {
-			_, err := pkg.ParseConfig(configFile.Name())
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("failed to parse image config file: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `this is...` into pkg.Config"))
+				data, err := os.ReadFile(config.Rotation.StateFile)
+				Expect(err).ToNot(HaveOccurred())
+				var saved map[string]interface{}
+				Expect(json.Unmarshal(data, &saved)).To(Succeed())
+				Expect(saved).To(HaveLen(1))
+				Expect(saved).To(HaveKey(HavePrefix("sequential:sources:")))
+			})
+
+			It("keeps a separate place for each config file that shares the state file", func() {
+				dir := GinkgoT().TempDir()
+				other := *config
+				other.Sources = []string{
+					"https://www.example.com/mario.jpg",
+					"https://www.example.com/luigi.jpg",
+				}
+				var configs []*pkg.Config
+				for i, c := range []*pkg.Config{config, &other} {
+					configFile := filepath.Join(dir, fmt.Sprintf("config%d.yaml", i))
+					data, err := yaml.Marshal(c)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(os.WriteFile(configFile, data, 0644)).To(Succeed())
+					read, err := pkg.ReadConfig(configFile)
+					Expect(err).ToNot(HaveOccurred())
+					configs = append(configs, read)
+				}
+
+				for i := 0; i < 2; i++ {
+					for _, c := range configs {
+						_, err := c.GenerateImage(300, 200)
+						Expect(err).ToNot(HaveOccurred())
+					}
+				}
+
+				Expect(fetched()).To(Equal([]string{
+					"https://www.example.com/link.jpg",
+					"https://www.example.com/mario.jpg",
+					"https://www.example.com/zelda.jpg",
+					"https://www.example.com/luigi.jpg",
+				}))
+			})
+
+			It("keeps a separate place for each list of images that shares the state file", func() {
+				other := *config
+				other.Sources = []string{
+					"https://www.example.com/mario.jpg",
+					"https://www.example.com/luigi.jpg",
+				}
+
+				for i := 0; i < 2; i++ {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = other.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+				}
+
+				Expect(fetched()).To(Equal([]string{
+					"https://www.example.com/link.jpg",
+					"https://www.example.com/mario.jpg",
+					"https://www.example.com/zelda.jpg",
+					"https://www.example.com/luigi.jpg",
+				}))
+			})
+
+			When("using shuffle", func() {
+				BeforeEach(func() {
+					config.Rotation.Strategy = "shuffle"
+				})
+
+				It("shows every image once before repeating", func() {
+					for i := 0; i < 6; i++ {
+						_, err := config.GenerateImage(300, 200)
+						Expect(err).ToNot(HaveOccurred())
+					}
+
+					sources := fetched()
+					Expect(sources[:3]).To(ConsistOf(config.Sources))
+					Expect(sources[3:]).To(ConsistOf(config.Sources))
+					Expect(sources[3]).ToNot(Equal(sources[2]))
+				})
+			})
+
+			When("using random", func() {
+				BeforeEach(func() {
+					config.Rotation.Strategy = "random"
+					randomInt := internal.RandomInt
+					DeferCleanup(func() { internal.RandomInt = randomInt })
+					internal.RandomInt = func(n int) int {
+						Expect(n).To(Equal(3))
+						return 2
+					}
+				})
+
+				It("shows a random image", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fetched()).To(Equal([]string{"https://www.example.com/ganon.jpg"}))
+				})
+			})
+
+			When("using daily", func() {
+				var now time.Time
+
+				BeforeEach(func() {
+					config.Rotation.Strategy = "daily"
+					now = time.Date(2026, 10, 18, 0, 5, 0, 0, time.Local)
+					internal.Now = func() time.Time { return now }
+					DeferCleanup(func() { internal.Now = time.Now })
+				})
+
+				It("shows the same image for the whole day", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					now = now.Add(23 * time.Hour)
+					_, err = config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					now = now.Add(time.Hour)
+					_, err = config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					sources := fetched()
+					Expect(sources[0]).To(Equal(sources[1]))
+					Expect(sources[2]).ToNot(Equal(sources[1]))
+				})
+			})
 		})
-	})
 
-	When("the config file has missing source", func() {
-		BeforeEach(func() {
-			config := pkg.Config{
-				Source: "",
-				Scale:  "resize",
-			}
-			var err error
-			configFileContents, err = json.Marshal(config)
-			Expect(err).ToNot(HaveOccurred())
+		Context("with a directory source", func() {
+			var (
+				config *pkg.Config
+				dir    string
+			)
+
+			BeforeEach(func() {
+				dir = GinkgoT().TempDir()
+				for _, file := range []string{"a.jpg", "b.jpg", "c.png", "readme.txt"} {
+					Expect(os.WriteFile(filepath.Join(dir, file), []byte("image data"), 0644)).To(Succeed())
+				}
+
+				httpGetter.Stub = func(string, *internal.HttpOptions) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+
+				newImage.Returns(returnedImage)
+
+				config = &pkg.Config{
+					Source: dir,
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					Directory: &pkg.DirectoryType{
+						Exclude: []string{"b.*"},
+					},
+					Rotation: &pkg.RotationType{
+						StateFile: filepath.Join(GinkgoT().TempDir(), "rotation.json"),
+					},
+				}
+			})
+
+			It("picks an image from the directory on each run", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, _ := httpGetter.ArgsForCall(0)
+				Expect(source).To(Equal(filepath.Join(dir, "a.jpg")))
+				source, _ = httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal(filepath.Join(dir, "c.png")))
+			})
+
+			It("skips images that cannot be decoded", func() {
+				imageDecoder.ReturnsOnCall(0, nil, errors.New("image decoding failed"))
+
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, _ := httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal(filepath.Join(dir, "c.png")))
+			})
+
+			When("none of the images can be decoded", func() {
+				BeforeEach(func() {
+					imageDecoder.Returns(nil, errors.New("image decoding failed"))
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("no usable images found in directory: " + dir))
+					var noImagesErr *internal.NoImagesError
+					Expect(errors.As(err, &noImagesErr)).To(BeTrue())
+				})
+			})
+
+			When("the directory has no images", func() {
+				BeforeEach(func() {
+					config.Directory.Extensions = []string{"webp"}
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("no usable images found in directory: " + dir))
+					Expect(httpGetter.CallCount()).To(Equal(0))
+				})
+			})
 		})
 
-		It("returns an error", func() {
-			_, err := pkg.ParseConfig(configFile.Name())
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(Equal("config file is not valid: missing image source"))
+		Context("with a feed source", func() {
+			var config *pkg.Config
+
+			BeforeEach(func() {
+				feed := `<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/"><channel>
+<item><title>Mountains</title><link>https://photos.example.com/mountains</link><pubDate>Sat, 17 Oct 2026 08:00:00 +0000</pubDate>
+<media:content url="https://photos.example.com/mountains.jpg" medium="image"/></item>
+<item><title>Beach</title><link>https://photos.example.com/beach</link><pubDate>Sun, 18 Oct 2026 08:00:00 +0000</pubDate>
+<enclosure url="/beach.jpg" type="image/jpeg"/></item>
+</channel></rss>`
+				httpGetter.Stub = func(source string, _ *internal.HttpOptions) (*http.Response, error) {
+					if source == "https://photos.example.com/feed.xml" {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Content-Type": []string{"application/rss+xml"}},
+							Body:       io.NopCloser(strings.NewReader(feed)),
+						}, nil
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+				newImage.Returns(returnedImage)
+
+				config = &pkg.Config{
+					Type:   pkg.SourceTypeFeed,
+					Source: "https://photos.example.com/feed.xml",
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					Rotation: &pkg.RotationType{
+						StateFile: filepath.Join(GinkgoT().TempDir(), "rotation.json"),
+					},
+				}
+			})
+
+			It("uses the image from the newest item", func() {
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, _ := httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal("https://photos.example.com/beach.jpg"))
+
+				Expect(config.SourceInfo()).To(Equal(&pkg.SourceInfo{
+					Source:   "https://photos.example.com/feed.xml",
+					Location: "https://photos.example.com/beach.jpg",
+					Title:    "Beach",
+					Link:     "https://photos.example.com/beach",
+				}))
+			})
+
+			When("rotating through the items", func() {
+				BeforeEach(func() {
+					config.Feed = &pkg.FeedType{Item: pkg.FeedItemRotate}
+				})
+
+				It("uses the next item on each run", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(config.SourceInfo().Title).To(Equal("Mountains"))
+
+					_, err = config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(config.SourceInfo().Title).To(Equal("Beach"))
+
+					source, _ := httpGetter.ArgsForCall(3)
+					Expect(source).To(Equal("https://photos.example.com/beach.jpg"))
+				})
+			})
+
+			When("an item's image is a local file", func() {
+				BeforeEach(func() {
+					httpGetter.Stub = nil
+					httpGetter.Returns(&http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`<rss><channel><item><title>Secret</title><enclosure url="file:///tmp/secret.png" type="image/png"/></item></channel></rss>`)),
+					}, nil)
+				})
+
+				It("returns an error without reading the file", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("refusing to fetch file:///tmp/secret.png, found in https://photos.example.com/feed.xml: links must use http, https or the same scheme as the document"))
+					Expect(httpGetter.CallCount()).To(Equal(1))
+				})
+			})
+
+			When("the feed has no images", func() {
+				BeforeEach(func() {
+					httpGetter.Stub = nil
+					httpGetter.Returns(&http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`<rss><channel><item><title>News</title></item></channel></rss>`)),
+					}, nil)
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("no items with images found in feed: https://photos.example.com/feed.xml"))
+				})
+			})
+
+			When("the feed cannot be fetched", func() {
+				BeforeEach(func() {
+					httpGetter.Stub = nil
+					httpGetter.Returns(&http.Response{
+						Status:     "404 Not Found",
+						StatusCode: http.StatusNotFound,
+						Header:     http.Header{"Content-Type": []string{"text/html"}},
+						Body:       io.NopCloser(strings.NewReader("not found")),
+					}, nil)
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("failed to fetch feed (https://photos.example.com/feed.xml): server responded with 404 Not Found (content type: text/html)"))
+				})
+			})
+		})
+
+		Context("with a JSON source", func() {
+			var (
+				config   *pkg.Config
+				document string
+			)
+
+			BeforeEach(func() {
+				document = `{"data": {"items": [{"url": "/images/today.jpg", "title": "Today"}]}}`
+				httpGetter.Stub = func(source string, _ *internal.HttpOptions) (*http.Response, error) {
+					if source == "https://api.example.com/potd" {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Content-Type": []string{"application/json"}},
+							Body:       io.NopCloser(strings.NewReader(document)),
+						}, nil
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+
+				config = &pkg.Config{
+					Type:   pkg.SourceTypeJson,
+					Source: "https://api.example.com/potd",
+					Json: &pkg.JsonType{
+						Path:         "data.items[0].url",
+						FallbackPath: "data.url",
+					},
+					Scale: "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+			})
+
+			It("uses the image URL found in the document", func() {
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, _ := httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal("https://api.example.com/images/today.jpg"))
+				Expect(config.SourceInfo().Location).To(Equal("https://api.example.com/images/today.jpg"))
+			})
+
+			When("the image URL is only at the fallback path", func() {
+				BeforeEach(func() {
+					document = `{"data": {"items": [], "url": "https://cdn.example.com/fallback.jpg"}}`
+				})
+
+				It("uses the fallback path", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					source, _ := httpGetter.ArgsForCall(1)
+					Expect(source).To(Equal("https://cdn.example.com/fallback.jpg"))
+				})
+			})
+
+			When("the image URL is a local file", func() {
+				BeforeEach(func() {
+					document = `{"data": {"url": "file:///tmp/secret.png"}}`
+				})
+
+				It("returns an error without reading the file", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("refusing to fetch file:///tmp/secret.png, found in https://api.example.com/potd: links must use http, https or the same scheme as the document"))
+					Expect(httpGetter.CallCount()).To(Equal(1))
+				})
+			})
+
+			When("the image URL is not in the document", func() {
+				BeforeEach(func() {
+					document = `{"data": {"items": []}}`
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("failed to find the image URL in JSON document (https://api.example.com/potd): index 0 is out of range at data.items (length 0) (fallback path: key \"url\" not found at data)"))
+					Expect(httpGetter.CallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("with an HTML source", func() {
+			var (
+				config *pkg.Config
+				page   string
+			)
+
+			BeforeEach(func() {
+				page = `<html><head><title>Webcam</title><meta property="og:image" content="/snapshot.jpg"></head></html>`
+				httpGetter.Stub = func(source string, _ *internal.HttpOptions) (*http.Response, error) {
+					if source == "https://example.com/webcam" {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Content-Type": []string{"text/html"}},
+							Body:       io.NopCloser(strings.NewReader(page)),
+						}, nil
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+
+				config = &pkg.Config{
+					Type:   pkg.SourceTypeHtml,
+					Source: "https://example.com/webcam",
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+			})
+
+			It("uses the og:image of the page", func() {
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, _ := httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal("https://example.com/snapshot.jpg"))
+				Expect(config.SourceInfo()).To(Equal(&pkg.SourceInfo{
+					Source:   "https://example.com/webcam",
+					Location: "https://example.com/snapshot.jpg",
+					Title:    "Webcam",
+					Link:     "https://example.com/webcam",
+				}))
+			})
+
+			When("the page has no og:image", func() {
+				BeforeEach(func() {
+					page = `<html><body>
+<img src="/icon.png" width="16" height="16">
+<img src="/banner.png">
+<img src="/photo.jpg">
+</body></html>`
+					imageDecoder.ReturnsOnCall(0, image.NewRGBA(image.Rect(0, 0, 728, 90)), nil)
+				})
+
+				It("uses the first large image on the page", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(httpGetter.CallCount()).To(Equal(3))
+					source, _ := httpGetter.ArgsForCall(1)
+					Expect(source).To(Equal("https://example.com/banner.png"))
+					source, _ = httpGetter.ArgsForCall(2)
+					Expect(source).To(Equal("https://example.com/photo.jpg"))
+					Expect(config.SourceInfo().Location).To(Equal("https://example.com/photo.jpg"))
+				})
+			})
+
+			When("the page links to local files", func() {
+				BeforeEach(func() {
+					page = `<html><head><meta property="og:image" content="file:///tmp/secret.png"></head><body>
+<img src="s3://bucket/secret.png">
+<img src="/photo.jpg">
+</body></html>`
+				})
+
+				It("skips them without reading them", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(httpGetter.CallCount()).To(Equal(2))
+					source, _ := httpGetter.ArgsForCall(1)
+					Expect(source).To(Equal("https://example.com/photo.jpg"))
+					Expect(imageDecoder.CallCount()).To(Equal(1))
+				})
+			})
+
+			When("the page has no usable image", func() {
+				BeforeEach(func() {
+					page = `<html><body><img src="/icon.png" width="16" height="16"></body></html>`
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("no usable image found on page: https://example.com/webcam"))
+					Expect(httpGetter.CallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("with a templated source", func() {
+			var config *pkg.Config
+
+			BeforeEach(func() {
+				now := time.Date(2026, time.October, 18, 3, 30, 0, 0, time.UTC)
+				internal.Now = func() time.Time { return now }
+				DeferCleanup(func() { internal.Now = time.Now })
+
+				config = &pkg.Config{
+					Source: "https://comics.example.com/{{.Year}}/{{.Month}}/{{.Day}}.png?w={{.Width}}&h={{.Height}}",
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+			})
+
+			It("fills in the date and the size of the image", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(httpGetter.CallCount()).To(Equal(1))
+				source, _ := httpGetter.ArgsForCall(0)
+				Expect(source).To(Equal("https://comics.example.com/2026/10/18.png?w=300&h=200"))
+			})
+
+			When("a timezone and offset are configured", func() {
+				BeforeEach(func() {
+					config.Template = &pkg.TemplateType{
+						Timezone: "America/Chicago",
+						Offset:   "yesterday",
+					}
+				})
+
+				It("uses the time in that timezone, moved by the offset", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					source, _ := httpGetter.ArgsForCall(0)
+					Expect(source).To(Equal("https://comics.example.com/2026/10/16.png?w=300&h=200"))
+				})
+			})
+
+			When("last known good is enabled", func() {
+				BeforeEach(func() {
+					config.LastKnownGood = &pkg.LastKnownGoodType{Enabled: true}
+				})
+
+				It("saves the image under the template", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(lastKnownGoodSaver.CallCount()).To(Equal(1))
+					_, source, _ := lastKnownGoodSaver.ArgsForCall(0)
+					Expect(source).To(Equal(config.Source))
+				})
+			})
+		})
+
+		Context("with an S3 prefix source", func() {
+			var config *pkg.Config
+
+			BeforeEach(func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.URL.Path).To(Equal("/photos/"))
+					Expect(r.URL.Query().Get("prefix")).To(Equal("2026/"))
+					w.Header().Set("Content-Type", "application/xml")
+					_, _ = w.Write([]byte(`<ListBucketResult>
+<Contents><Key>2026/beach.jpg</Key></Contents>
+<Contents><Key>2026/notes.txt</Key></Contents>
+<Contents><Key>2026/nested/lake.png</Key></Contents>
+<Contents><Key>2026/sunset.jpg</Key></Contents>
+</ListBucketResult>`))
+				}))
+				DeferCleanup(server.Close)
+
+				httpGetter.Stub = func(string, *internal.HttpOptions) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(bytes.NewReader(pngHeader)),
+					}, nil
+				}
+				newImage.Returns(returnedImage)
+
+				config = &pkg.Config{
+					Source: "s3://photos/2026/",
+					S3: &pkg.S3Type{
+						Endpoint:        server.URL,
+						AccessKeyID:     "AKIAEXAMPLE",
+						SecretAccessKey: "secret",
+						PathStyle:       true,
+					},
+					Scale: "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					Rotation: &pkg.RotationType{
+						StateFile: filepath.Join(GinkgoT().TempDir(), "rotation.json"),
+					},
+				}
+			})
+
+			It("rotates through the images under the prefix", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(httpGetter.CallCount()).To(Equal(2))
+				source, options := httpGetter.ArgsForCall(0)
+				Expect(source).To(Equal("s3://photos/2026/beach.jpg"))
+				Expect(options.S3.Endpoint).To(Equal(config.S3.Endpoint))
+				Expect(options.S3.PathStyle).To(BeTrue())
+				source, _ = httpGetter.ArgsForCall(1)
+				Expect(source).To(Equal("s3://photos/2026/sunset.jpg"))
+				Expect(config.SourceInfo().Location).To(Equal("s3://photos/2026/sunset.jpg"))
+			})
+		})
+
+		Context("with the source read from stdin", func() {
+			var config *pkg.Config
+
+			BeforeEach(func() {
+				internal.Stdin = bytes.NewReader(pngHeader)
+				DeferCleanup(func() { internal.Stdin = os.Stdin })
+
+				config = &pkg.Config{
+					Source: pkg.StdinSource,
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+			})
+
+			It("decodes the image from stdin", func() {
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(0))
+				Expect(imageDecoder.CallCount()).To(Equal(1))
+				r, _ := imageDecoder.ArgsForCall(0)
+				data, err := io.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(pngHeader))
+			})
+
+			When("stdin is empty", func() {
+				BeforeEach(func() {
+					internal.Stdin = bytes.NewReader(nil)
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("failed to read image from stdin: no data"))
+				})
+			})
+		})
+
+		Context("with an archive source", func() {
+			var (
+				config  *pkg.Config
+				archive string
+			)
+
+			BeforeEach(func() {
+				archive = filepath.Join(GinkgoT().TempDir(), "photos.zip")
+				f, err := os.Create(archive)
+				Expect(err).ToNot(HaveOccurred())
+				w := zip.NewWriter(f)
+				for _, name := range []string{"2025/beach.jpg", "2026/mountains.jpg", "2026/lake.png", "readme.txt"} {
+					member, err := w.Create(name)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = member.Write([]byte(name + " image data"))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(w.Close()).To(Succeed())
+				Expect(f.Close()).To(Succeed())
+
+				newImage.Returns(returnedImage)
+
+				config = &pkg.Config{
+					Source: archive,
+					Archive: &pkg.ArchiveType{
+						Members: "2026/*",
+					},
+					Scale: "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					Rotation: &pkg.RotationType{
+						StateFile: filepath.Join(GinkgoT().TempDir(), "rotation.json"),
+					},
+				}
+			})
+
+			It("rotates through the matching images in the archive", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(config.SourceInfo().Location).To(Equal(archive + "#2026/lake.png"))
+
+				_, err = config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(config.SourceInfo().Location).To(Equal(archive + "#2026/mountains.jpg"))
+
+				Expect(httpGetter.CallCount()).To(Equal(0))
+				Expect(imageDecoder.CallCount()).To(Equal(2))
+				r, _ := imageDecoder.ArgsForCall(1)
+				data, err := io.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("2026/mountains.jpg image data"))
+			})
+
+			When("no images match", func() {
+				BeforeEach(func() {
+					config.Archive.Members = "2027/*"
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("no usable images found in archive: " + archive))
+				})
+			})
+		})
+
+		Context("with an MJPEG source", func() {
+			var config *pkg.Config
+
+			BeforeEach(func() {
+				body = &responseBody{Reader: strings.NewReader("--frame\r\nContent-Type: image/jpeg\r\n\r\nfirst frame\r\n" +
+					"--frame\r\nContent-Type: image/jpeg\r\n\r\nsecond frame\r\n" +
+					"--frame\r\nContent-Type: image/jpeg\r\n\r\nthird frame\r\n")}
+				httpGetter.Returns(&http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"multipart/x-mixed-replace; boundary=frame"}},
+					Body:       body,
+				}, nil)
+
+				config = &pkg.Config{
+					Type:   pkg.SourceTypeMjpeg,
+					Source: "http://camera.local/stream.mjpg",
+					Mjpeg: &pkg.MjpegType{
+						Frame: 2,
+					},
+					Scale: "resize",
+					Cache: &pkg.CacheType{
+						Directory: GinkgoT().TempDir(),
+					},
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+			})
+
+			It("decodes the requested frame and closes the stream", func() {
+				img, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(img).To(Equal(returnedImage))
+
+				Expect(httpGetter.CallCount()).To(Equal(1))
+				_, options := httpGetter.ArgsForCall(0)
+				Expect(options.Cache).To(BeNil())
+				Expect(body.closed).To(BeTrue())
+
+				Expect(imageDecoder.CallCount()).To(Equal(1))
+				r, _ := imageDecoder.ArgsForCall(0)
+				data, err := io.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("second frame"))
+				Expect(config.SourceInfo().Location).To(Equal("http://camera.local/stream.mjpg"))
+			})
+
+			When("the stream is not an MJPEG stream", func() {
+				BeforeEach(func() {
+					httpGetter.Returns(res, nil)
+				})
+
+				It("returns an error", func() {
+					_, err := config.GenerateImage(300, 200)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("failed to fetch frame from stream (http://camera.local/stream.mjpg): the server sent a single image, not an MJPEG stream, so frame 2 is not available"))
+				})
+			})
+		})
+
+		Context("with gif, tiff and jpeg settings", func() {
+			It("passes the frame and page selection, the orientation setting, and the size of the image, to the decoder", func() {
+				config := &pkg.Config{
+					Source: "https://example.com/radar.gif",
+					Gif: &pkg.GifType{
+						Frame:    "by-time",
+						Interval: "15m",
+					},
+					Tiff: &pkg.TiffType{
+						Page: 2,
+					},
+					Jpeg: &pkg.JpegType{
+						IgnoreOrientation: true,
+					},
+					Scale: "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(imageDecoder.CallCount()).To(Equal(1))
+				_, options := imageDecoder.ArgsForCall(0)
+				Expect(options).To(Equal(&internal.DecodeOptions{
+					MaxPixels:   internal.DefaultMaxPixels,
+					GifFrame:    internal.GifFrameByTime,
+					GifInterval: 15 * time.Minute,
+					TiffPage:    2,
+					Width:       300,
+					Height:      200,
+					Fit:         internal.SvgFitResize,
+
+					IgnoreOrientation: true,
+				}))
+			})
+		})
+
+		Context("with last known good enabled", func() {
+			var (
+				config     *pkg.Config
+				fetchedAt  time.Time
+				staleImage *image.RGBA
+			)
+
+			BeforeEach(func() {
+				config = &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "resize",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					LastKnownGood: &pkg.LastKnownGoodType{
+						Enabled:   true,
+						Directory: "/var/lib/images",
+					},
+				}
+
+				fetchedAt = time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC)
+				lastKnownGoodLoader.Returns([]byte("old image data"), fetchedAt, nil)
+				staleImage = image.NewRGBA(image.Rect(0, 0, 640, 480))
+			})
+
+			It("saves a copy of the fetched image", func() {
+				_, err := config.GenerateImage(300, 200)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(lastKnownGoodSaver.CallCount()).To(Equal(1))
+				directory, source, data := lastKnownGoodSaver.ArgsForCall(0)
+				Expect(directory).To(Equal("/var/lib/images"))
+				Expect(source).To(Equal("https://www.example.com/link.jpg"))
+				Expect(data).To(Equal(pngHeader))
+				Expect(lastKnownGoodLoader.CallCount()).To(Equal(0))
+			})
+
+			When("fetching the image fails", func() {
+				BeforeEach(func() {
+					httpGetter.Returns(nil, errors.New("http get failed"))
+					imageDecoder.Returns(staleImage, nil)
+				})
+
+				It("returns the last known good image with a stale image error", func() {
+					img, err := config.GenerateImage(300, 200)
+					Expect(img).To(Equal(returnedImage))
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("using the last known good copy of the image from 2026-10-17T08:30:00Z: failed to fetch image (https://www.example.com/link.jpg): http get failed"))
+
+					var staleErr *pkg.StaleImageError
+					Expect(errors.As(err, &staleErr)).To(BeTrue())
+					Expect(staleErr.FetchedAt).To(Equal(fetchedAt))
+
+					Expect(lastKnownGoodLoader.CallCount()).To(Equal(1))
+					directory, source := lastKnownGoodLoader.ArgsForCall(0)
+					Expect(directory).To(Equal("/var/lib/images"))
+					Expect(source).To(Equal("https://www.example.com/link.jpg"))
+
+					Expect(imageDecoder.CallCount()).To(Equal(1))
+					Expect(lastKnownGoodSaver.CallCount()).To(Equal(0))
+					_, _, im, _, _, _ := scale.ArgsForCall(0)
+					Expect(im).To(Equal(staleImage))
+				})
+
+				When("there is no last known good image", func() {
+					BeforeEach(func() {
+						lastKnownGoodLoader.Returns(nil, time.Time{}, errors.New("no such file"))
+					})
+
+					It("returns the original error", func() {
+						img, err := config.GenerateImage(300, 200)
+						Expect(img).To(BeNil())
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): http get failed"))
+					})
+				})
+			})
+
+			When("decoding the image fails", func() {
+				BeforeEach(func() {
+					imageDecoder.ReturnsOnCall(0, nil, errors.New("image decoding failed"))
+					imageDecoder.ReturnsOnCall(1, staleImage, nil)
+				})
+
+				It("returns the last known good image with a stale image error", func() {
+					img, err := config.GenerateImage(300, 200)
+					Expect(img).To(Equal(returnedImage))
+					var staleErr *pkg.StaleImageError
+					Expect(errors.As(err, &staleErr)).To(BeTrue())
+					Expect(imageDecoder.CallCount()).To(Equal(2))
+					Expect(lastKnownGoodSaver.CallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("unknown scale type", func() {
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "smoothjazz",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("unknown image scale type: smoothjazz"))
+			})
+		})
+
+		When("getting the image fails", func() {
+			BeforeEach(func() {
+				httpGetter.Returns(nil, errors.New("http get failed"))
+			})
+
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "cover",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): http get failed"))
+			})
+		})
+
+		When("the server responds with an error status", func() {
+			BeforeEach(func() {
+				res.StatusCode = http.StatusNotFound
+				res.Status = "404 Not Found"
+				res.Header.Set("Content-Type", "text/html")
+			})
+
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "cover",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): server responded with 404 Not Found (content type: text/html)"))
+
+				var statusErr *internal.HttpStatusError
+				Expect(errors.As(err, &statusErr)).To(BeTrue())
+				Expect(statusErr.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(imageDecoder.CallCount()).To(Equal(0))
+				Expect(body.closed).To(BeTrue())
+			})
+		})
+
+		When("the response is not an image", func() {
+			BeforeEach(func() {
+				body.Reader = bytes.NewReader([]byte("<html><body>Please log in to the Wi-Fi</body></html>"))
+				res.Header.Set("Content-Type", "image/jpeg")
+			})
+
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "cover",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): response is not an image (content type: image/jpeg, detected content type: text/html; charset=utf-8)"))
+
+				var contentTypeErr *internal.ContentTypeError
+				Expect(errors.As(err, &contentTypeErr)).To(BeTrue())
+				Expect(imageDecoder.CallCount()).To(Equal(0))
+			})
+		})
+
+		When("the image is larger than the download limit", func() {
+			BeforeEach(func() {
+				res.ContentLength = 2048
+			})
+
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "cover",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+					Limits: &pkg.LimitsType{
+						MaxBytes:  1024,
+						MaxPixels: 1000000,
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("failed to fetch image (https://www.example.com/link.jpg): image exceeds maxBytes: the image data is larger than the limit of 1024 bytes"))
+
+				var limitErr *internal.LimitError
+				Expect(errors.As(err, &limitErr)).To(BeTrue())
+				Expect(imageDecoder.CallCount()).To(Equal(0))
+			})
+		})
+
+		When("decoding the image fails", func() {
+			BeforeEach(func() {
+				imageDecoder.Returns(nil, errors.New("image decoding failed"))
+			})
+
+			It("returns an error", func() {
+				config := &pkg.Config{
+					Source: "https://www.example.com/link.jpg",
+					Scale:  "cover",
+					Background: &pkg.BackgroundType{
+						Color: "red",
+					},
+				}
+
+				_, err := config.GenerateImage(200, 300)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("failed to decode image (https://www.example.com/link.jpg): image decoding failed"))
+			})
+		})
+	})
+})
+
+var _ = Describe("ParseConfig", func() {
+	var (
+		configFile         *os.File
+		configFileContents []byte
+	)
+
+	JustBeforeEach(func() {
+		var err error
+		configFile, err = os.CreateTemp("", "blank-config.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = configFile.Write(configFileContents)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	BeforeEach(func() {
+		config := pkg.Config{
+			Source: "https://www.example.com/link.jpg",
+			Scale:  "contain",
+			Background: &pkg.BackgroundType{
+				Color: "red",
+			},
+		}
+		var err error
+		configFileContents, err = yaml.Marshal(config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.Remove(configFile.Name())).To(Succeed())
+	})
+
+	It("parses the image config file", func() {
+		config, err := pkg.ParseConfig(configFile.Name())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
+		Expect(config.Scale).To(Equal("contain"))
+		Expect(config.Background.Color).To(Equal("red"))
+	})
+
+	Context("config file is json formatted", func() {
+		BeforeEach(func() {
+			config := pkg.Config{
+				Source: "https://www.example.com/impa.jpg",
+				Scale:  "cover",
+				Background: &pkg.BackgroundType{
+					Color: "blue",
+				},
+			}
+			var err error
+			configFileContents, err = json.Marshal(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("parses just fine", func() {
+			config, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Source).To(Equal("https://www.example.com/impa.jpg"))
+			Expect(config.Scale).To(Equal("cover"))
+			Expect(config.Background.Color).To(Equal("blue"))
+		})
+	})
+
+	Context("config file has a list of sources", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source:\n  - https://cdn.example.com/impa.jpg\n  - /home/pi/impa.jpg\nscale: cover\n")
+		})
+
+		It("parses the list", func() {
+			config, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Source).To(Equal("https://cdn.example.com/impa.jpg"))
+			Expect(config.FallbackSources).To(Equal([]string{"/home/pi/impa.jpg"}))
+		})
+	})
+
+	When("reading the config file fails", func() {
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig("this file does not exist")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to read image config file: open this file does not exist: no such file or directory"))
+		})
+	})
+
+	When("parsing the config file fails", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("this is invalid yaml!")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("failed to parse image config file: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `this is...` into pkg.Config"))
+		})
+	})
+
+	When("the config file has missing source", func() {
+		BeforeEach(func() {
+			config := pkg.Config{
+				Source: "",
+				Scale:  "resize",
+			}
+			var err error
+			configFileContents, err = json.Marshal(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: missing image source"))
+		})
+	})
+
+	When("the config file has an unsupported source scheme", func() {
+		BeforeEach(func() {
+			config := pkg.Config{
+				Source: "ftp://www.example.com/impa.jpg",
+				Scale:  "resize",
+			}
+			var err error
+			configFileContents, err = json.Marshal(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid image source: unsupported image source scheme: \"ftp\""))
+		})
+	})
+
+	When("the config file has an invalid source in a list", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source:\n  - https://cdn.example.com/impa.jpg\n  - ftp://ftp.example.com/impa.jpg\nscale: cover\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid image source (ftp://ftp.example.com/impa.jpg): unsupported image source scheme: \"ftp\""))
+		})
+	})
+
+	When("the config file has a malformed data URI source", func() {
+		BeforeEach(func() {
+			config := pkg.Config{
+				Source: "data:image/png;base64,not base64!",
+				Scale:  "resize",
+			}
+			var err error
+			configFileContents, err = json.Marshal(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid image source: data URI has invalid base64 data: illegal base64 data at input byte 9"))
+		})
+	})
+
+	When("the config file has invalid http settings", func() {
+		BeforeEach(func() {
+			config := pkg.Config{
+				Source: "https://www.example.com/impa.jpg",
+				Scale:  "resize",
+				Http: &pkg.HttpType{
+					BasicAuth: &pkg.BasicAuthType{
+						Username: "link",
+						Password: "triforce",
+					},
+					BearerToken: "abc123",
+				},
+			}
+			var err error
+			configFileContents, err = json.Marshal(config)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid http settings: only one of basicAuth and bearerToken can be used"))
+		})
+	})
+
+	When("the config file has an invalid http timeout", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://www.example.com/impa.jpg\nscale: resize\nhttp:\n  timeout: soon\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid http settings: invalid timeout \"soon\": time: invalid duration \"soon\""))
+		})
+	})
+
+	When("the config file has invalid cache settings", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://www.example.com/impa.jpg\nscale: resize\ncache:\n  maxAge: -5m\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid cache settings: invalid maxAge \"-5m\": must not be negative"))
+		})
+	})
+
+	When("the config file has both source and sources", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://www.example.com/impa.jpg\nsources:\n  - https://www.example.com/link.jpg\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: only one of source and sources can be used"))
+		})
+	})
+
+	When("the config file has both fallbackSources and sources", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("fallbackSources:\n  - /home/pi/impa.jpg\nsources:\n  - https://www.example.com/link.jpg\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: fallbackSources cannot be used with sources"))
+		})
+	})
+
+	When("the config file has an invalid rotation strategy", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("sources:\n  - https://www.example.com/link.jpg\nrotation:\n  strategy: backwards\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid rotation: strategy value is invalid: \"backwards\", must be one of sequential, shuffle, random, daily"))
+		})
+	})
+
+	When("the config file has an invalid source type", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("type: podcast\nsource: https://example.com/feed.xml\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: type value is invalid: \"podcast\", must be one of image, feed, json, html, archive, mjpeg"))
+		})
+	})
+
+	When("the config file has an invalid feed item", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("type: feed\nsource: https://example.com/feed.xml\nfeed:\n  item: oldest\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid feed settings: item value is invalid: \"oldest\", must be one of newest, rotate"))
+		})
+	})
+
+	When("the config file has a json source without a path", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("type: json\nsource: https://api.example.com/potd\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: json settings are required when type is json"))
+		})
+	})
+
+	When("the config file has an invalid json path", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("type: json\nsource: https://api.example.com/potd\njson:\n  path: data.items[first].url\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid json settings: invalid path: invalid index \"first\" in path \"data.items[first].url\""))
+		})
+	})
+
+	When("the config file has an invalid source template", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://comics.example.com/{{.Week}}.png\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("config file is not valid: invalid image source: failed to expand source template: "))
+			Expect(err.Error()).To(ContainSubstring("can't evaluate field Week"))
+		})
+	})
+
+	When("the config file has an invalid template timezone", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://comics.example.com/{{.Date}}.png\ntemplate:\n  timezone: Mars/Olympus_Mons\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid template settings: invalid timezone: unknown time zone Mars/Olympus_Mons"))
+		})
+	})
+
+	When("the config file has an invalid gif frame", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://example.com/radar.gif\ngif:\n  frame: first\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid gif settings: invalid frame \"first\", must be a frame number, last, middle or by-time"))
+		})
+	})
+
+	When("the config file selects gif frames by time without an interval", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://example.com/radar.gif\ngif:\n  frame: by-time\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid gif settings: interval is required when frame is by-time"))
+		})
+	})
+
+	When("the config file has a gif interval without selecting frames by time", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://example.com/radar.gif\ngif:\n  frame: last\n  interval: 1h\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid gif settings: interval can only be used when frame is by-time"))
+		})
+	})
+
+	When("the config file has a negative tiff page", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: /home/pi/scans/album.tiff\ntiff:\n  page: -1\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid tiff settings: page must not be negative"))
+		})
+	})
+
+	When("the config file has incomplete s3 credentials", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: s3://photos/frame.jpg\ns3:\n  accessKeyId: AKIAEXAMPLE\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid s3 settings: accessKeyId and secretAccessKey must be used together"))
+		})
+	})
+
+	When("the config file has a client certificate without a key", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://images.internal/frame.jpg\nhttp:\n  clientCert: /etc/frame/client.crt\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid http settings: a client certificate and key must be used together"))
+		})
+	})
+
+	When("the config file has an invalid proxy", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: https://images.example.com/frame.jpg\nhttp:\n  proxy: proxy.example.com:3128\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid http settings: invalid proxy URL: \"proxy.example.com:3128\", must be an http, https or socks5 URL"))
+		})
+	})
+
+	When("the config file reads a feed from stdin", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("type: feed\nsource: \"-\"\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid image source: stdin can only be used for images, not feed sources"))
+		})
+	})
+
+	When("the config file has an invalid directory pattern", func() {
+		BeforeEach(func() {
+			configFileContents = []byte("source: /home/pi/images\ndirectory:\n  include:\n    - \"[a-\"\nscale: resize\n")
+		})
+
+		It("returns an error", func() {
+			_, err := pkg.ParseConfig(configFile.Name())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("config file is not valid: invalid directory settings: invalid pattern \"[a-\": syntax error in pattern"))
 		})
 	})
 
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type DirectoryType struct {
+	Include    []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude    []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	Recursive  bool     `json:"recursive,omitempty" yaml:"recursive,omitempty"`
+	Extensions []string `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+func (d *DirectoryType) options() *internal.DirectoryOptions {
+	if d == nil {
+		return nil
+	}
+
+	extensions := make([]string, len(d.Extensions))
+	for i, extension := range d.Extensions {
+		extensions[i] = "." + strings.TrimPrefix(strings.ToLower(extension), ".")
+	}
+	return &internal.DirectoryOptions{
+		Include:    d.Include,
+		Exclude:    d.Exclude,
+		Recursive:  d.Recursive,
+		Extensions: extensions,
+	}
+}
+
+func (d *DirectoryType) Validate() error {
+	for _, pattern := range append(slices.Clone(d.Include), d.Exclude...) {
+		if err := internal.ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid pattern \"%s\": %w", pattern, err)
+		}
+	}
+	for _, extension := range d.Extensions {
+		if strings.TrimPrefix(extension, ".") == "" {
+			return fmt.Errorf("extensions must not be empty")
+		}
+	}
+	return nil
+}
+
+// fetchDirectoryImage picks an image from the directory, moving on to another if it cannot be fetched or decoded.
+func (c *Config) fetchDirectoryImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	directory, err := internal.FilePath(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := internal.ListImageFiles(directory, c.Directory.options())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list images in directory (%s): %w", directory, err)
+	}
+
+	data, im, location, err := c.fetchOneOf("directory:"+info.Source, files, c.fetchAndDecode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pick an image from directory (%s): %w", directory, err)
+	}
+	if im == nil {
+		return nil, nil, &internal.NoImagesError{Directory: directory}
+	}
+	info.Location = location
+	return data, im, nil
+}
+
+// fetchOneOf picks one of the candidate images using the rotation, moving on to another if it cannot be fetched or decoded.
+// The key should be based on the configured source, rather than the expanded template, so that the position is kept between runs.
+// The image is nil if none of the candidates could be used.
+func (c *Config) fetchOneOf(key string, candidates []string, fetch func(string) ([]byte, image.Image, error)) ([]byte, image.Image, string, error) {
+	for len(candidates) > 0 {
+		candidate, err := c.Rotation.pick(key, candidates)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		data, im, err := fetch(candidate)
+		if err == nil {
+			return data, im, candidate, nil
+		}
+		internal.Logger.Printf("skipping %s", err)
+		candidates = slices.DeleteFunc(candidates, func(c string) bool { return c == candidate })
+	}
+	return nil, nil, "", nil
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+const (
+	FeedItemNewest = "newest"
+	FeedItemRotate = "rotate"
+)
+
+type FeedType struct {
+	Item string `json:"item,omitempty" yaml:"item,omitempty"`
+}
+
+func (f *FeedType) item() string {
+	if f == nil || f.Item == "" {
+		return FeedItemNewest
+	}
+	return f.Item
+}
+
+func (f *FeedType) Validate() error {
+	switch f.item() {
+	case FeedItemNewest, FeedItemRotate:
+		return nil
+	default:
+		return fmt.Errorf("item value is invalid: \"%s\", must be one of newest, rotate", f.Item)
+	}
+}
+
+// fetchFeedImage fetches an RSS or Atom feed, and then the image from the newest item, or the next item in the rotation.
+func (c *Config) fetchFeedImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	data, err := c.fetchDocument(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch feed (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	items, err := internal.ParseFeed(data, source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read feed (%s): %w", internal.DescribeSource(source), err)
+	}
+	if len(items) == 0 {
+		return nil, nil, &internal.NoFeedImagesError{Feed: internal.DescribeSource(source)}
+	}
+
+	item := internal.NewestFeedItem(items)
+	if c.Feed.item() == FeedItemRotate {
+		images := make([]string, len(items))
+		for i, item := range items {
+			images[i] = item.ImageURL
+		}
+		picked, err := c.Rotation.pick("feed:"+info.Source, images)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to pick an item from feed (%s): %w", internal.DescribeSource(source), err)
+		}
+		for _, candidate := range items {
+			if candidate.ImageURL == picked {
+				item = candidate
+				break
+			}
+		}
+	}
+
+	if err := internal.CheckLinkedURL(source, item.ImageURL); err != nil {
+		return nil, nil, err
+	}
+	info.Location = item.ImageURL
+	info.Title = item.Title
+	info.Link = item.Link
+	return c.fetchAndDecode(item.ImageURL)
+}
@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// fetchFirstImage returns the first of the sources that can be fetched and decoded.
+func (c *Config) fetchFirstImage(sources []string, width, height int) (image.Image, error) {
+	var errs []error
+	for _, source := range sources {
+		im, err := c.fetchImage(source, width, height)
+		if err == nil {
+			return im, nil
+		}
+		if len(sources) > 1 {
+			internal.Logger.Printf("%s", err)
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, &SourcesError{Errors: errs}
+}
+
+// fetchImage fetches and decodes the image for one of the configured sources, and saves it as the last known good copy.
+// The last known good copy of a templated source is saved under the template, so that it can be used whatever the time.
+func (c *Config) fetchImage(source string, width, height int) (image.Image, error) {
+	location, err := c.expandSource(source, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SourceInfo{Source: source}
+	data, im, err := c.resolveImage(location, info)
+	if err != nil {
+		return nil, err
+	}
+
+	c.info = info
+	c.saveLastKnownGood(source, data)
+	return im, nil
+}
+
+// resolveImage finds the image for a configured source, which may refer to a collection of images rather than a single image.
+func (c *Config) resolveImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	switch {
+	case source == StdinSource:
+		info.Location = source
+		return c.readStdin()
+	case c.Type == SourceTypeFeed:
+		return c.fetchFeedImage(source, info)
+	case c.Type == SourceTypeJson:
+		return c.fetchJsonImage(source, info)
+	case c.Type == SourceTypeHtml:
+		return c.fetchHtmlImage(source, info)
+	case c.Type == SourceTypeMjpeg:
+		return c.fetchMjpegImage(source, info)
+	case c.Type == SourceTypeArchive || (c.Type == "" && internal.IsArchive(source)):
+		return c.fetchArchiveImage(source, info)
+	case internal.IsDirectory(source):
+		return c.fetchDirectoryImage(source, info)
+	case isS3Prefix(source):
+		return c.fetchS3PrefixImage(source, info)
+	}
+	info.Location = source
+	return c.fetchAndDecode(source)
+}
+
+func (c *Config) fetchAndDecode(location string) ([]byte, image.Image, error) {
+	data, err := c.fetchData(location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch image (%s): %w", internal.DescribeSource(location), err)
+	}
+
+	im, err := internal.DecodeImage(bytes.NewReader(data), c.decodeOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image (%s): %w", internal.DescribeSource(location), err)
+	}
+	return data, im, nil
+}
+
+func (c *Config) fetchData(source string) ([]byte, error) {
+	res, err := internal.HttpGet(source, c.httpOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := internal.ValidateResponse(res)
+	if err != nil {
+		return nil, err
+	}
+	if err := internal.CheckContentLength(res.ContentLength, c.Limits.maxBytes()); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(internal.LimitReader(body, c.Limits.maxBytes()))
+}
+
+// fetchDocument fetches a resource that refers to the image, such as a feed, JSON document or web page, rather than the image itself.
+func (c *Config) fetchDocument(source string) ([]byte, error) {
+	res, err := internal.HttpGet(source, c.httpOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if err := internal.CheckStatus(res); err != nil {
+		return nil, err
+	}
+	if err := internal.CheckContentLength(res.ContentLength, c.Limits.maxBytes()); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(internal.LimitReader(res.Body, c.Limits.maxBytes()))
+}
@@ -0,0 +1,34 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type GifType struct {
+	Frame    string `json:"frame,omitempty" yaml:"frame,omitempty"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+func (g *GifType) Validate() error {
+	if err := internal.ValidateGifFrame(g.Frame); err != nil {
+		return err
+	}
+	if g.Interval != "" {
+		interval, err := time.ParseDuration(g.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval \"%s\": %w", g.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+		if g.Frame != internal.GifFrameByTime {
+			return fmt.Errorf("interval can only be used when frame is %s", internal.GifFrameByTime)
+		}
+	} else if g.Frame == internal.GifFrameByTime {
+		return fmt.Errorf("interval is required when frame is %s", internal.GifFrameByTime)
+	}
+	return nil
+}
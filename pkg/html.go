@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// maxPageImageAttempts is how many <img> tags are fetched, looking for one that is large enough, before giving up.
+const maxPageImageAttempts = 10
+
+type HtmlType struct {
+	MinImageSize int `json:"minImageSize,omitempty" yaml:"minImageSize,omitempty"`
+}
+
+func (h *HtmlType) minImageSize() int {
+	if h == nil || h.MinImageSize == 0 {
+		return internal.DefaultMinPageImageSize
+	}
+	return h.MinImageSize
+}
+
+func (h *HtmlType) Validate() error {
+	if h.MinImageSize < 0 {
+		return fmt.Errorf("minImageSize must not be negative")
+	}
+	return nil
+}
+
+// fetchHtmlImage fetches a web page, and then the image named by its og:image or twitter:image tags,
+// or the first <img> on the page that is large enough.
+func (c *Config) fetchHtmlImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	data, err := c.fetchDocument(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch page (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	page, err := internal.ParsePage(data, source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read page (%s): %w", internal.DescribeSource(source), err)
+	}
+	info.Title = page.Title
+	info.Link = source
+
+	for _, location := range page.MetaImages {
+		if err := internal.CheckLinkedURL(source, location); err != nil {
+			internal.Logger.Printf("skipping %s", err)
+			continue
+		}
+		data, im, err := c.fetchAndDecode(location)
+		if err == nil {
+			info.Location = location
+			return data, im, nil
+		}
+		internal.Logger.Printf("skipping %s", err)
+	}
+
+	minSize := c.Html.minImageSize()
+	attempts := 0
+	for _, img := range page.Images {
+		if (img.Width > 0 && img.Width < minSize) || (img.Height > 0 && img.Height < minSize) {
+			continue
+		}
+		if err := internal.CheckLinkedURL(source, img.URL); err != nil {
+			internal.Logger.Printf("skipping %s", err)
+			continue
+		}
+		if attempts == maxPageImageAttempts {
+			break
+		}
+		attempts++
+
+		data, im, err := c.fetchAndDecode(img.URL)
+		if err != nil {
+			internal.Logger.Printf("skipping %s", err)
+			continue
+		}
+		if size := im.Bounds().Size(); size.X < minSize || size.Y < minSize {
+			internal.Logger.Printf("skipping %s: image is too small (%dx%d)", internal.DescribeSource(img.URL), size.X, size.Y)
+			continue
+		}
+		info.Location = img.URL
+		return data, im, nil
+	}
+	return nil, nil, &internal.NoPageImageError{Page: internal.DescribeSource(source)}
+}
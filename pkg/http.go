@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type BasicAuthType struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+type HttpType struct {
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	UserAgent   string            `json:"userAgent,omitempty" yaml:"userAgent,omitempty"`
+	BasicAuth   *BasicAuthType    `json:"basicAuth,omitempty" yaml:"basicAuth,omitempty"`
+	BearerToken string            `json:"bearerToken,omitempty" yaml:"bearerToken,omitempty"`
+
+	Timeout         string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	ConnectTimeout  string `json:"connectTimeout,omitempty" yaml:"connectTimeout,omitempty"`
+	Retries         *int   `json:"retries,omitempty" yaml:"retries,omitempty"`
+	RetryBackoff    string `json:"retryBackoff,omitempty" yaml:"retryBackoff,omitempty"`
+	MaxRetryBackoff string `json:"maxRetryBackoff,omitempty" yaml:"maxRetryBackoff,omitempty"`
+
+	CAFile             string `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty" yaml:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty" yaml:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty" yaml:"insecureSkipVerify,omitempty"`
+	Proxy              string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+}
+
+func (h *HttpType) options() *internal.HttpOptions {
+	if h == nil {
+		return nil
+	}
+
+	options := &internal.HttpOptions{
+		Headers:     h.Headers,
+		UserAgent:   h.UserAgent,
+		BearerToken: h.BearerToken,
+		Retries:     internal.DefaultRetries,
+
+		CAFile:             h.CAFile,
+		ClientCertFile:     h.ClientCert,
+		ClientKeyFile:      h.ClientKey,
+		InsecureSkipVerify: h.InsecureSkipVerify,
+		Proxy:              h.Proxy,
+	}
+	if h.BasicAuth != nil {
+		options.Username = h.BasicAuth.Username
+		options.Password = h.BasicAuth.Password
+	}
+	if h.Retries != nil {
+		options.Retries = *h.Retries
+	}
+
+	// Durations have already been checked by Validate
+	options.Timeout, _ = parseDuration(h.Timeout)
+	options.ConnectTimeout, _ = parseDuration(h.ConnectTimeout)
+	options.RetryBackoff, _ = parseDuration(h.RetryBackoff)
+	options.MaxRetryBackoff, _ = parseDuration(h.MaxRetryBackoff)
+	return options
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return duration, nil
+}
+
+func (h *HttpType) Validate() error {
+	for name, value := range h.Headers {
+		if !isHeaderName(name) {
+			return fmt.Errorf("invalid header name: \"%s\"", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid value for header \"%s\": must not contain line breaks", name)
+		}
+		if (h.BasicAuth != nil || h.BearerToken != "") && strings.EqualFold(name, "Authorization") {
+			return fmt.Errorf("the Authorization header cannot be combined with basicAuth or bearerToken")
+		}
+	}
+
+	if h.BasicAuth != nil {
+		if h.BasicAuth.Username == "" {
+			return fmt.Errorf("basicAuth is missing a username")
+		}
+		if h.BearerToken != "" {
+			return fmt.Errorf("only one of basicAuth and bearerToken can be used")
+		}
+	}
+
+	if strings.ContainsAny(h.UserAgent, "\r\n") || strings.ContainsAny(h.BearerToken, "\r\n") {
+		return fmt.Errorf("userAgent and bearerToken must not contain line breaks")
+	}
+
+	durations := []struct{ name, value string }{
+		{"timeout", h.Timeout},
+		{"connectTimeout", h.ConnectTimeout},
+		{"retryBackoff", h.RetryBackoff},
+		{"maxRetryBackoff", h.MaxRetryBackoff},
+	}
+	for _, duration := range durations {
+		if _, err := parseDuration(duration.value); err != nil {
+			return fmt.Errorf("invalid %s \"%s\": %w", duration.name, duration.value, err)
+		}
+	}
+
+	if h.Retries != nil && *h.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+
+	if _, err := internal.LoadTLSConfig(h.CAFile, h.ClientCert, h.ClientKey, h.InsecureSkipVerify); err != nil {
+		return err
+	}
+	if h.Proxy != "" {
+		if _, err := internal.ParseProxyURL(h.Proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return true
+}
+
+type CacheType struct {
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
+	MaxSize   int64  `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+	MaxAge    string `json:"maxAge,omitempty" yaml:"maxAge,omitempty"`
+}
+
+func (c *CacheType) options() *internal.CacheOptions {
+	// MaxAge has already been checked by Validate
+	maxAge, _ := parseDuration(c.MaxAge)
+	return &internal.CacheOptions{
+		Directory: c.Directory,
+		MaxSize:   c.MaxSize,
+		MaxAge:    maxAge,
+	}
+}
+
+func (c *CacheType) Validate() error {
+	if c.MaxSize < 0 {
+		return fmt.Errorf("maxSize must not be negative")
+	}
+	if _, err := parseDuration(c.MaxAge); err != nil {
+		return fmt.Errorf("invalid maxAge \"%s\": %w", c.MaxAge, err)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+package pkg
+
+type JpegType struct {
+	IgnoreOrientation bool `json:"ignoreOrientation,omitempty" yaml:"ignoreOrientation,omitempty"`
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type JsonType struct {
+	Path         string `json:"path" yaml:"path"`
+	FallbackPath string `json:"fallbackPath,omitempty" yaml:"fallbackPath,omitempty"`
+}
+
+func (j *JsonType) Validate() error {
+	if j.Path == "" {
+		return fmt.Errorf("missing path")
+	}
+	if err := internal.ValidateJSONPath(j.Path); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if j.FallbackPath != "" {
+		if err := internal.ValidateJSONPath(j.FallbackPath); err != nil {
+			return fmt.Errorf("invalid fallbackPath: %w", err)
+		}
+	}
+	return nil
+}
+
+// imageURL finds the image URL in the JSON document, using the fallback path if it is not at the main path.
+func (j *JsonType) imageURL(data []byte) (string, error) {
+	location, err := internal.LookupJSONPath(data, j.Path)
+	if err == nil || j.FallbackPath == "" {
+		return location, err
+	}
+
+	location, fallbackErr := internal.LookupJSONPath(data, j.FallbackPath)
+	if fallbackErr != nil {
+		return "", fmt.Errorf("%w (fallback path: %w)", err, fallbackErr)
+	}
+	return location, nil
+}
+
+// fetchJsonImage fetches a JSON document, and then the image whose URL is found in it.
+func (c *Config) fetchJsonImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	data, err := c.fetchDocument(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch JSON document (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	location, err := c.Json.imageURL(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find the image URL in JSON document (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	location = internal.ResolveURL(source, location)
+	if err := internal.CheckLinkedURL(source, location); err != nil {
+		return nil, nil, err
+	}
+	info.Location = location
+	return c.fetchAndDecode(location)
+}
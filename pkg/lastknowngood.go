@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type LastKnownGoodType struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Directory string `json:"directory,omitempty" yaml:"directory,omitempty"`
+}
+
+func (l *LastKnownGoodType) isEnabled() bool {
+	return l != nil && l.Enabled
+}
+
+// StaleImageError is returned, along with the image, when the image was generated from the last known good copy of the source.
+type StaleImageError struct {
+	Source    string
+	FetchedAt time.Time
+	Err       error
+}
+
+func (e *StaleImageError) Error() string {
+	return fmt.Sprintf("using the last known good copy of the image from %s: %s", e.FetchedAt.Format(time.RFC3339), e.Err)
+}
+
+func (e *StaleImageError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Config) saveLastKnownGood(source string, data []byte) {
+	if !c.LastKnownGood.isEnabled() {
+		return
+	}
+	if err := internal.SaveLastKnownGood(c.LastKnownGood.Directory, source, data); err != nil {
+		internal.Logger.Printf("warning: unable to save the last known good copy of %s: %s", internal.DescribeSource(source), err)
+	}
+}
+
+// useLastKnownGood decodes the most recently fetched copy of the first source that has one, after fetching or decoding failed.
+// On success, the returned error is a StaleImageError. Otherwise, the original error is returned.
+func (c *Config) useLastKnownGood(sources []string, fetchErr error) (image.Image, error) {
+	for _, source := range sources {
+		data, fetchedAt, err := internal.LoadLastKnownGood(c.LastKnownGood.Directory, source)
+		if err != nil {
+			internal.Logger.Printf("no last known good copy of %s is available: %s", internal.DescribeSource(source), err)
+			continue
+		}
+
+		im, err := internal.DecodeImage(bytes.NewReader(data), c.decodeOptions())
+		if err != nil {
+			internal.Logger.Printf("failed to decode the last known good copy of %s: %s", internal.DescribeSource(source), err)
+			continue
+		}
+
+		c.info = &SourceInfo{Source: source, Location: source}
+		staleErr := &StaleImageError{Source: source, FetchedAt: fetchedAt, Err: fetchErr}
+		internal.Logger.Printf("warning: %s", staleErr)
+		return im, staleErr
+	}
+	return nil, fetchErr
+}
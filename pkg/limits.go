@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type LimitsType struct {
+	MaxBytes  int64 `json:"maxBytes,omitempty" yaml:"maxBytes,omitempty"`
+	MaxPixels int64 `json:"maxPixels,omitempty" yaml:"maxPixels,omitempty"`
+}
+
+func (l *LimitsType) maxBytes() int64 {
+	if l == nil || l.MaxBytes == 0 {
+		return internal.DefaultMaxBytes
+	}
+	return l.MaxBytes
+}
+
+func (l *LimitsType) decodeOptions() *internal.DecodeOptions {
+	options := &internal.DecodeOptions{MaxPixels: internal.DefaultMaxPixels}
+	if l != nil && l.MaxPixels != 0 {
+		options.MaxPixels = l.MaxPixels
+	}
+	return options
+}
+
+// decodeOptions returns the options used to decode every image, from the limits and format settings.
+func (c *Config) decodeOptions() *internal.DecodeOptions {
+	options := c.Limits.decodeOptions()
+	options.Width, options.Height = c.size.X, c.size.Y
+	options.Fit = c.Scale
+	if c.Gif != nil {
+		options.GifFrame = c.Gif.Frame
+		options.GifInterval, _ = time.ParseDuration(c.Gif.Interval)
+	}
+	if c.Tiff != nil {
+		options.TiffPage = c.Tiff.Page
+	}
+	if c.Jpeg != nil {
+		options.IgnoreOrientation = c.Jpeg.IgnoreOrientation
+	}
+	return options
+}
+
+func (l *LimitsType) Validate() error {
+	if l.MaxBytes < 0 {
+		return fmt.Errorf("maxBytes must not be negative")
+	}
+	if l.MaxPixels < 0 {
+		return fmt.Errorf("maxPixels must not be negative")
+	}
+	return nil
+}
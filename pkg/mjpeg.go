@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type MjpegType struct {
+	Frame int `json:"frame,omitempty" yaml:"frame,omitempty"`
+}
+
+func (m *MjpegType) frame() int {
+	if m == nil || m.Frame == 0 {
+		return 1
+	}
+	return m.Frame
+}
+
+func (m *MjpegType) Validate() error {
+	if m.Frame < 0 {
+		return fmt.Errorf("frame must not be negative")
+	}
+	return nil
+}
+
+// fetchMjpegImage connects to an MJPEG stream, reads one frame and disconnects.
+func (c *Config) fetchMjpegImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	options := c.httpOptions()
+	if options != nil && options.Cache != nil {
+		// A stream never ends, so it cannot be cached
+		uncached := *options
+		uncached.Cache = nil
+		options = &uncached
+	}
+
+	data, err := func() ([]byte, error) {
+		res, err := internal.HttpGet(source, options)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		if err := internal.CheckStatus(res); err != nil {
+			return nil, err
+		}
+		return internal.ReadMjpegFrame(res, c.Mjpeg.frame(), c.Limits.maxBytes())
+	}()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch frame from stream (%s): %w", internal.DescribeSource(source), err)
+	}
+
+	im, err := internal.DecodeImage(bytes.NewReader(data), c.decodeOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode frame from stream (%s): %w", internal.DescribeSource(source), err)
+	}
+	info.Location = source
+	return data, im, nil
+}
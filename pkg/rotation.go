@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+const (
+	RotationSequential = "sequential"
+	RotationShuffle    = "shuffle"
+	RotationRandom     = "random"
+	RotationDaily      = "daily"
+)
+
+// rotationStateExpiry is how long the position of a rotation is kept after it was last used.
+const rotationStateExpiry = 30 * 24 * time.Hour
+
+type RotationType struct {
+	Strategy  string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	StateFile string `json:"stateFile,omitempty" yaml:"stateFile,omitempty"`
+}
+
+func (r *RotationType) strategy() string {
+	if r == nil || r.Strategy == "" {
+		return RotationSequential
+	}
+	return r.Strategy
+}
+
+func (r *RotationType) stateFile() string {
+	if r == nil || r.StateFile == "" {
+		return filepath.Join(internal.DefaultStateDirectory(), "rotation.json")
+	}
+	return r.StateFile
+}
+
+func (r *RotationType) Validate() error {
+	switch r.strategy() {
+	case RotationSequential, RotationShuffle, RotationRandom, RotationDaily:
+		return nil
+	default:
+		return fmt.Errorf("strategy value is invalid: \"%s\", must be one of sequential, shuffle, random, daily", r.Strategy)
+	}
+}
+
+type rotationState struct {
+	Next      int      `json:"next,omitempty"`
+	Last      string   `json:"last,omitempty"`
+	Remaining []string `json:"remaining,omitempty"`
+	// Used is when the rotation last picked a candidate, so that rotations that are no longer configured can be removed.
+	Used time.Time `json:"used,omitempty"`
+}
+
+// pick chooses which of the candidates to show on this run.
+// The position of each rotation is saved in the state file under the given key, which should not change when the candidates do.
+func (r *RotationType) pick(key string, candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("nothing to rotate through")
+	}
+
+	switch r.strategy() {
+	case RotationRandom:
+		return candidates[internal.RandomInt(len(candidates))], nil
+	case RotationDaily:
+		now := internal.Now()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+		return candidates[int(day%int64(len(candidates)))], nil
+	}
+
+	states, err := r.loadStates()
+	if err != nil {
+		return "", err
+	}
+
+	key = r.strategy() + ":" + key
+	state := states[key]
+	var picked string
+	if r.strategy() == RotationShuffle {
+		picked = state.nextShuffled(candidates)
+	} else {
+		picked = state.nextSequential(candidates)
+	}
+	state.Used = internal.Now()
+	states[key] = state
+
+	if err := r.saveStates(states); err != nil {
+		return "", err
+	}
+	return picked, nil
+}
+
+// nextSequential returns the candidate after the last one shown, even if other candidates have been added or removed.
+// If the last one shown has been removed, the candidate that has taken its place is next.
+func (s *rotationState) nextSequential(candidates []string) string {
+	index := 0
+	if s.Next > 0 && s.Next <= len(candidates) && candidates[s.Next-1] == s.Last {
+		index = s.Next % len(candidates)
+	} else if last := slices.Index(candidates, s.Last); last >= 0 {
+		index = (last + 1) % len(candidates)
+	} else if s.Next > 0 {
+		index = (s.Next - 1) % len(candidates)
+	}
+
+	s.Next = index + 1
+	s.Last = candidates[index]
+	return s.Last
+}
+
+// nextShuffled returns a random candidate that has not been shown yet, starting over once every candidate has been shown.
+func (s *rotationState) nextShuffled(candidates []string) string {
+	remaining := []string{}
+	for _, candidate := range s.Remaining {
+		if slices.Contains(candidates, candidate) {
+			remaining = append(remaining, candidate)
+		}
+	}
+
+	choices := remaining
+	if len(remaining) == 0 {
+		remaining = slices.Clone(candidates)
+		// Avoid showing the same image twice in a row when starting over
+		choices = slices.DeleteFunc(slices.Clone(candidates), func(candidate string) bool {
+			return candidate == s.Last
+		})
+		if len(choices) == 0 {
+			choices = remaining
+		}
+	}
+
+	s.Last = choices[internal.RandomInt(len(choices))]
+	s.Remaining = slices.DeleteFunc(remaining, func(candidate string) bool {
+		return candidate == s.Last
+	})
+	return s.Last
+}
+
+func (r *RotationType) loadStates() (map[string]rotationState, error) {
+	states := map[string]rotationState{}
+	data, err := os.ReadFile(r.stateFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rotation state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		internal.Logger.Printf("warning: rotation state file %s is corrupt, starting over: %s", r.stateFile(), err)
+		return map[string]rotationState{}, nil
+	}
+	return states, nil
+}
+
+// saveStates writes the rotation positions, leaving out any that have not been used recently.
+func (r *RotationType) saveStates(states map[string]rotationState) error {
+	expired := internal.Now().Add(-rotationStateExpiry)
+	maps.DeleteFunc(states, func(key string, state rotationState) bool {
+		return state.Used.Before(expired)
+	})
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	path := r.stateFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write rotation state file: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rotation state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write rotation state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+type S3Type struct {
+	Endpoint        string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Region          string `json:"region,omitempty" yaml:"region,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty" yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty" yaml:"secretAccessKey,omitempty"`
+	SessionToken    string `json:"sessionToken,omitempty" yaml:"sessionToken,omitempty"`
+	CredentialsFile string `json:"credentialsFile,omitempty" yaml:"credentialsFile,omitempty"`
+	Profile         string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	PathStyle       bool   `json:"pathStyle,omitempty" yaml:"pathStyle,omitempty"`
+}
+
+func (s *S3Type) options() *internal.S3Options {
+	if s == nil {
+		return nil
+	}
+	return &internal.S3Options{
+		Endpoint:        s.Endpoint,
+		Region:          s.Region,
+		AccessKeyID:     s.AccessKeyID,
+		SecretAccessKey: s.SecretAccessKey,
+		SessionToken:    s.SessionToken,
+		CredentialsFile: s.CredentialsFile,
+		Profile:         s.Profile,
+		PathStyle:       s.PathStyle,
+	}
+}
+
+func (s *S3Type) Validate() error {
+	if s.Endpoint != "" {
+		if err := internal.ValidateS3Endpoint(s.Endpoint); err != nil {
+			return err
+		}
+	}
+	if (s.AccessKeyID == "") != (s.SecretAccessKey == "") {
+		return fmt.Errorf("accessKeyId and secretAccessKey must be used together")
+	}
+	if s.SessionToken != "" && s.AccessKeyID == "" {
+		return fmt.Errorf("sessionToken requires accessKeyId and secretAccessKey")
+	}
+	return nil
+}
+
+// isS3Prefix returns true if the source refers to many objects in S3, such as s3://bucket/photos/
+func isS3Prefix(source string) bool {
+	if internal.SourceScheme(source) != internal.SchemeS3 {
+		return false
+	}
+	location, err := internal.ParseS3Source(source)
+	return err == nil && location.IsPrefix()
+}
+
+// fetchS3PrefixImage picks an image from the objects under an S3 prefix, using the same filtering as a directory.
+func (c *Config) fetchS3PrefixImage(source string, info *SourceInfo) ([]byte, image.Image, error) {
+	location, err := internal.ParseS3Source(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := internal.ListS3Objects(source, c.httpOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list images in S3 (%s): %w", source, err)
+	}
+
+	objects := []string{}
+	options := c.Directory.options()
+	for _, key := range keys {
+		if options.Matches(strings.TrimPrefix(key, location.Key)) {
+			objects = append(objects, "s3://"+location.Bucket+"/"+key)
+		}
+	}
+
+	data, im, object, err := c.fetchOneOf("s3:"+info.Source, objects, c.fetchAndDecode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pick an image from S3 (%s): %w", source, err)
+	}
+	if im == nil {
+		return nil, nil, &internal.NoImagesError{Directory: source}
+	}
+	info.Location = object
+	return data, im, nil
+}
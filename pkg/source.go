@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	SourceTypeImage   = "image"
+	SourceTypeFeed    = "feed"
+	SourceTypeJson    = "json"
+	SourceTypeHtml    = "html"
+	SourceTypeArchive = "archive"
+	SourceTypeMjpeg   = "mjpeg"
+)
+
+// SourceInfo describes where the most recently generated image came from.
+type SourceInfo struct {
+	// Source is the configured source that the image came from.
+	Source string
+	// Location is the image itself, when the source refers to a feed or a collection of images.
+	// Images in an archive are written as the archive, followed by # and the name of the file in the archive.
+	Location string
+	// Title and Link describe the feed item or web page that the image came from, for use in captions.
+	Title string
+	Link  string
+}
+
+// SourceInfo returns where the most recently generated image came from, or nil if no image has been generated.
+func (c *Config) SourceInfo() *SourceInfo {
+	return c.info
+}
+
+// configFields is Config without its UnmarshalYAML and UnmarshalJSON methods, so that they can decode the rest of the config.
+type configFields Config
+
+// UnmarshalYAML reads the config, allowing source to be a list of sources to try in order.
+// The first is used as Source and the rest are added to the start of FallbackSources.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Named Config, so that decoding errors name the type that was being read.
+	type Config configFields
+
+	var probe struct {
+		Source interface{} `yaml:"source"`
+	}
+	if err := unmarshal(&probe); err == nil {
+		if _, isList := probe.Source.([]interface{}); isList {
+			return c.unmarshalSourceListYAML(unmarshal)
+		}
+	}
+	return unmarshal((*Config)(c))
+}
+
+func (c *Config) unmarshalSourceListYAML(unmarshal func(interface{}) error) error {
+	type Config configFields
+
+	var list struct {
+		Source []string `yaml:"source"`
+	}
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+
+	// The rest of the config is decoded without the list, which would not fit in Source.
+	var fields, rest yaml.MapSlice
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.Key != "source" {
+			rest = append(rest, field)
+		}
+	}
+	data, err := yaml.Marshal(rest)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, (*Config)(c)); err != nil {
+		return err
+	}
+
+	c.addSourceList(list.Source)
+	return nil
+}
+
+// UnmarshalJSON reads the config, allowing source to be a list of sources to try in order, as UnmarshalYAML does.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type Config configFields
+
+	fields := struct {
+		*Config
+		Source json.RawMessage `json:"source"`
+	}{Config: (*Config)(c)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields.Source) == 0 || string(fields.Source) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(fields.Source, &c.Source); err == nil {
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(fields.Source, &list); err != nil {
+		return fmt.Errorf("source must be a string or a list of strings")
+	}
+	c.addSourceList(list)
+	return nil
+}
+
+// addSourceList sets Source to the first of a list of sources, and adds the rest to the start of FallbackSources.
+func (c *Config) addSourceList(sources []string) {
+	c.Source = ""
+	if len(sources) > 0 {
+		c.Source = sources[0]
+		c.FallbackSources = append(append([]string{}, sources[1:]...), c.FallbackSources...)
+	}
+}
+
+// sourceChain returns Source followed by FallbackSources.
+func (c *Config) sourceChain() []string {
+	return append([]string{c.Source}, c.FallbackSources...)
+}
+
+// sourcesToTry returns the sources for this run: either the list of fallback sources, or the one picked from the slideshow.
+func (c *Config) sourcesToTry() ([]string, error) {
+	if len(c.Sources) == 0 {
+		return c.sourceChain(), nil
+	}
+
+	source, err := c.Rotation.pick(c.sourcesKey(), c.Sources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick the next image source: %w", err)
+	}
+	return []string{source}, nil
+}
+
+// sourcesKey returns the key that the slideshow position is saved under, so that configs sharing a state file each keep their own place.
+// It comes from the config file, which stays the same when sources are added or removed.
+// Configs that were not read from a file use the list of sources instead, and start over when it changes.
+func (c *Config) sourcesKey() string {
+	id := c.path
+	if id == "" {
+		id = strings.Join(c.Sources, "\n")
+	}
+	hash := sha256.Sum256([]byte(id))
+	return "sources:" + hex.EncodeToString(hash[:])
+}
+
+// SourcesError combines the errors from every source that was tried.
+type SourcesError struct {
+	Errors []error
+}
+
+func (e *SourcesError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("all %d image sources failed: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+func (e *SourcesError) Unwrap() []error {
+	return e.Errors
+}
@@ -0,0 +1,74 @@
+package pkg_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/petewall/eink-radiator-image-source-image/pkg"
+)
+
+var _ = Describe("Config sources", func() {
+	It("reads source from a string", func() {
+		var config pkg.Config
+		Expect(yaml.Unmarshal([]byte("source: https://www.example.com/link.jpg\nscale: cover\n"), &config)).To(Succeed())
+		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
+		Expect(config.FallbackSources).To(BeEmpty())
+		Expect(config.Scale).To(Equal("cover"))
+
+		config = pkg.Config{}
+		Expect(json.Unmarshal([]byte(`{"source": "https://www.example.com/link.jpg", "scale": "cover"}`), &config)).To(Succeed())
+		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
+		Expect(config.FallbackSources).To(BeEmpty())
+		Expect(config.Scale).To(Equal("cover"))
+	})
+
+	It("reads the rest of a list of sources into fallbackSources", func() {
+		var config pkg.Config
+		Expect(yaml.Unmarshal([]byte("source:\n  - https://www.example.com/link.jpg\n  - /home/pi/link.jpg\nscale: cover\n"), &config)).To(Succeed())
+		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
+		Expect(config.FallbackSources).To(Equal([]string{"/home/pi/link.jpg"}))
+		Expect(config.Scale).To(Equal("cover"))
+
+		config = pkg.Config{}
+		Expect(json.Unmarshal([]byte(`{"source": ["https://www.example.com/link.jpg", "/home/pi/link.jpg"], "scale": "cover"}`), &config)).To(Succeed())
+		Expect(config.Source).To(Equal("https://www.example.com/link.jpg"))
+		Expect(config.FallbackSources).To(Equal([]string{"/home/pi/link.jpg"}))
+		Expect(config.Scale).To(Equal("cover"))
+	})
+
+	It("tries a list of sources before fallbackSources", func() {
+		var config pkg.Config
+		Expect(yaml.Unmarshal([]byte("source: [https://cdn.example.com/link.jpg, https://mirror.example.com/link.jpg]\nfallbackSources: [/home/pi/link.jpg]\n"), &config)).To(Succeed())
+		Expect(config.Source).To(Equal("https://cdn.example.com/link.jpg"))
+		Expect(config.FallbackSources).To(Equal([]string{"https://mirror.example.com/link.jpg", "/home/pi/link.jpg"}))
+	})
+
+	It("writes source as a string", func() {
+		config := pkg.Config{
+			Source:          "https://www.example.com/link.jpg",
+			FallbackSources: []string{"/home/pi/link.jpg"},
+			Scale:           "cover",
+		}
+		encoded, err := json.Marshal(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(encoded)).To(Equal(`{"source":"https://www.example.com/link.jpg","fallbackSources":["/home/pi/link.jpg"],"scale":"cover"}`))
+
+		var decoded pkg.Config
+		Expect(json.Unmarshal(encoded, &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(config))
+	})
+
+	It("rejects other types", func() {
+		var config pkg.Config
+		err := json.Unmarshal([]byte(`{"source": {"url": "https://www.example.com/link.jpg"}}`), &config)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("source must be a string or a list of strings"))
+
+		err = yaml.Unmarshal([]byte("source:\n  - url: https://www.example.com/link.jpg\n"), &config)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("yaml: unmarshal errors:\n  line 2: cannot unmarshal !!map into string"))
+	})
+})
@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// StdinSource is the source that reads the image from standard input.
+const StdinSource = "-"
+
+// readStdin reads and decodes the image from standard input, rather than fetching it.
+func (c *Config) readStdin() ([]byte, image.Image, error) {
+	data, err := io.ReadAll(internal.LimitReader(internal.Stdin, c.Limits.maxBytes()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read image from stdin: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("failed to read image from stdin: no data")
+	}
+
+	im, err := internal.DecodeImage(bytes.NewReader(data), c.decodeOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image (stdin): %w", err)
+	}
+	return data, im, nil
+}
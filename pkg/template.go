@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/petewall/eink-radiator-image-source-image/internal"
+)
+
+// exampleWidth and exampleHeight are used to check that templated sources can be expanded when the config is validated.
+const (
+	exampleWidth  = 800
+	exampleHeight = 480
+)
+
+type TemplateType struct {
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Offset   string `json:"offset,omitempty" yaml:"offset,omitempty"`
+}
+
+func (t *TemplateType) location() (*time.Location, error) {
+	if t == nil || t.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(t.Timezone)
+}
+
+// now returns the time used for templated sources, in the configured timezone and moved by the configured offset.
+func (t *TemplateType) now() (time.Time, error) {
+	location, err := t.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var offset string
+	if t != nil {
+		offset = t.Offset
+	}
+	days, duration, err := internal.ParseTimeOffset(offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return internal.Now().In(location).AddDate(0, 0, days).Add(duration), nil
+}
+
+func (t *TemplateType) Validate() error {
+	if _, err := t.location(); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	if _, _, err := internal.ParseTimeOffset(t.Offset); err != nil {
+		return err
+	}
+	return nil
+}
+
+// expandSource fills in the time and the size of the image being generated in a templated source.
+func (c *Config) expandSource(source string, width, height int) (string, error) {
+	if !internal.IsTemplate(source) {
+		return source, nil
+	}
+
+	now, err := c.Template.now()
+	if err != nil {
+		return "", err
+	}
+	expanded, err := internal.ExpandTemplate(source, internal.NewTemplateData(now, width, height))
+	if err != nil {
+		return "", fmt.Errorf("failed to expand source template: %w", err)
+	}
+	return expanded, nil
+}
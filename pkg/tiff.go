@@ -0,0 +1,14 @@
+package pkg
+
+import "fmt"
+
+type TiffType struct {
+	Page int `json:"page,omitempty" yaml:"page,omitempty"`
+}
+
+func (t *TiffType) Validate() error {
+	if t.Page < 0 {
+		return fmt.Errorf("page must not be negative")
+	}
+	return nil
+}